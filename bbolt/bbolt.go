@@ -66,6 +66,22 @@ func (s *Storage) Put(ctx context.Context, resp *http.Response) error {
 	return nil
 }
 
+func (s *Storage) Delete(ctx context.Context, req *http.Request) error {
+	if err := s.DB.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(s.Bucket)
+		if bucket == nil {
+			return errors.ErrBucketNotFound
+		}
+		if err := bucket.Delete([]byte(req.URL.String())); err != nil {
+			return fmt.Errorf("(*bbolt.Bucket).Delete failed: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("(*bbolt.DB).Update failed: %w", err)
+	}
+	return nil
+}
+
 // Open is a wrapper around bbolt.Open that returns an initialized Storage.
 func Open(path string, mode os.FileMode, options *bbolt.Options, bucket []byte) (*Storage, error) {
 	if bucket == nil {
@@ -0,0 +1,154 @@
+package ghtransport
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// callerKey is the context.Context key of the caller identity of a request.
+type callerKey struct{}
+
+// WithCaller returns a copy of the context tagging its requests with the caller identity (ex: the subsystem
+// making them), see Options.Budgets and (*Transport).Usage.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// callerFrom returns the caller identity of the request, or "" if untagged.
+func callerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// Budget limits the upstream requests of a caller (see WithCaller) that count against the rate limit, i.e.
+// those not answered with a 304 Not Modified. The struct tags allow it to be loaded from JSON or YAML.
+type Budget struct {
+	// Requests is the number of such upstream requests allowed per Window.
+	Requests int `json:"requests" yaml:"requests"`
+	// Window is how often the budget is replenished, it is never replenished if not positive.
+	Window Duration `json:"window,omitempty" yaml:"window,omitempty"`
+}
+
+// BudgetError is returned by RoundTrip when the caller of the request exhausted its Budget and no stale
+// response may be served instead.
+type BudgetError struct {
+	Caller string
+	Budget Budget
+	// Reset is when the budget is replenished, zero if never.
+	Reset time.Time
+}
+
+func (e *BudgetError) Error() string {
+	msg := "caller " + strconv.Quote(e.Caller) + " exhausted its budget of " + strconv.Itoa(e.Budget.Requests) + " upstream requests"
+	if !e.Reset.IsZero() {
+		msg += " until " + e.Reset.Format(time.RFC3339)
+	}
+	return msg
+}
+
+// CallerUsage reports the upstream requests of a caller (see WithCaller).
+type CallerUsage struct {
+	Caller string
+	// Requests is the number of upstream requests counting against the rate limit (not a 304 Not Modified).
+	Requests int
+	// NotModified is the number of upstream requests answered with a (free) 304 Not Modified.
+	NotModified int
+	// Stale is the number of requests served stale from the Storage as the budget was exhausted.
+	Stale int
+	// Rejected is the number of requests rejected with a *BudgetError as the budget was exhausted.
+	Rejected int
+	// WindowStart is when the current budget window started.
+	WindowStart time.Time
+	// WindowRequests is the number of Requests in the current budget window.
+	WindowRequests int
+}
+
+// budgets tracks the CallerUsage of each caller seen by a Transport.
+type budgets struct {
+	mu    sync.Mutex
+	usage map[string]*CallerUsage
+}
+
+// callerUsage returns the usage of the caller, starting a new budget window if the current one is over. The
+// budgets must be locked.
+func (t *Transport) callerUsage(caller string) *CallerUsage {
+	now := t.now()
+	usage, ok := t.budgets.usage[caller]
+	if !ok {
+		if t.budgets.usage == nil {
+			t.budgets.usage = make(map[string]*CallerUsage)
+		}
+		usage = &CallerUsage{Caller: caller, WindowStart: now}
+		t.budgets.usage[caller] = usage
+	}
+	if window := time.Duration(t.opts.Budgets[caller].Window); window > 0 && !now.Before(usage.WindowStart.Add(window)) {
+		usage.WindowStart, usage.WindowRequests = now, 0
+	}
+	return usage
+}
+
+// budget holds an upstream request back if its caller exhausted its Budget: the cached response is returned as
+// stale if it matches the request (evaluating the caller's own validators against it), otherwise it fails with
+// a *BudgetError. Concurrent requests may exceed the budget slightly, as a request only counts once its response
+// is known not to be a 304 Not Modified.
+func (t *Transport) budget(req *http.Request, cached *http.Response) (*http.Response, error) {
+	caller := callerFrom(req.Context())
+	budget, ok := t.opts.Budgets[caller]
+	if caller == "" || !ok {
+		return nil, nil
+	}
+
+	t.budgets.mu.Lock()
+	defer t.budgets.mu.Unlock()
+	usage := t.callerUsage(caller)
+	if usage.WindowRequests < budget.Requests {
+		return nil, nil
+	}
+	if cached != nil && t.identicalVary(req, cached) {
+		usage.Stale++
+		return t.freshResponse(req, cached, cacheStatusDetail(cacheStatusHit(t.opts.CacheName), "stale-if-over-budget")), nil
+	}
+	usage.Rejected++
+	var reset time.Time
+	if budget.Window > 0 {
+		reset = usage.WindowStart.Add(time.Duration(budget.Window))
+	}
+	return nil, &BudgetError{Caller: caller, Budget: budget, Reset: reset}
+}
+
+// noteBudget counts the upstream response to the request against the Budget of its caller (if tagged). Only
+// the requests subject to the budget are counted.
+func (t *Transport) noteBudget(req *http.Request, resp *http.Response) {
+	caller := callerFrom(req.Context())
+	if caller == "" {
+		return
+	}
+	t.budgets.mu.Lock()
+	defer t.budgets.mu.Unlock()
+	usage := t.callerUsage(caller)
+	if resp.StatusCode == http.StatusNotModified {
+		usage.NotModified++
+		return
+	}
+	usage.Requests++
+	usage.WindowRequests++
+}
+
+// Usage returns a snapshot of the upstream requests of every caller (see WithCaller), sorted by caller.
+func (t *Transport) Usage() []CallerUsage {
+	t.budgets.mu.Lock()
+	usages := make([]CallerUsage, 0, len(t.budgets.usage))
+	for caller := range t.budgets.usage {
+		usages = append(usages, *t.callerUsage(caller))
+	}
+	t.budgets.mu.Unlock()
+	slices.SortFunc(usages, func(a, b CallerUsage) int {
+		return cmp.Compare(a.Caller, b.Caller)
+	})
+	return usages
+}
@@ -0,0 +1,180 @@
+package ghtransport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransport_RoundTrip_Budgets(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	start := now
+	budget := Budget{Requests: 2, Window: Duration(time.Minute)}
+	tr := New(Options{
+		Storage: &mockStorage{
+			getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/repos/foo/cached" {
+					return nil, nil
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Etag": []string{`"tag1"`}},
+					Body:       io.NopCloser(strings.NewReader("cached")),
+				}, nil
+			},
+		},
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				if req.Header.Get("If-None-Match") != "" {
+					return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: http.NoBody}, nil
+				}
+				return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("content"))}, nil
+			},
+		},
+		Speculations: []Speculation{},
+		Budgets:      map[string]Budget{"noisy": budget},
+	})
+	tr.now = func() time.Time { return now }
+
+	roundTrip := func(caller, path string, ifNoneMatch ...string) (*http.Response, error) {
+		t.Helper()
+		ctx := context.Background()
+		if caller != "" {
+			ctx = WithCaller(ctx, caller)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com"+path, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		for _, etag := range ifNoneMatch {
+			req.Header.Add("If-None-Match", etag)
+		}
+		resp, err := tr.RoundTrip(req)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	// A 304 Not Modified does not count
+	if _, err := roundTrip("noisy", "/repos/foo/cached"); err != nil {
+		t.Fatalf("RoundTrip() within the budget error = %v", err)
+	}
+	for range 2 {
+		if _, err := roundTrip("noisy", "/repos/foo/bar"); err != nil {
+			t.Fatalf("RoundTrip() within the budget error = %v", err)
+		}
+	}
+	// Requests bypassing the cache are neither budgeted nor counted
+	if _, err := roundTrip("noisy", "/rate_limit"); err != nil {
+		t.Fatalf("RoundTrip() bypassing the cache error = %v", err)
+	}
+	// Other callers are not affected
+	for _, caller := range []string{"quiet", ""} {
+		if _, err := roundTrip(caller, "/repos/foo/bar"); err != nil {
+			t.Fatalf("RoundTrip() of %q error = %v", caller, err)
+		}
+	}
+
+	// Over budget, the cached response is served stale
+	resp, err := roundTrip("noisy", "/repos/foo/cached")
+	if err != nil {
+		t.Fatalf("RoundTrip() over the budget with a cached response error = %v", err)
+	}
+	if got, want := resp.Header.Get("Cache-Status"), withOperation(cacheStatusDetail(cacheStatusHit(CacheName), "stale-if-over-budget")); got != want {
+		t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, want)
+	}
+
+	// Over budget, the caller's own validators are evaluated against the stale response
+	resp, err = roundTrip("noisy", "/repos/foo/cached", `"tag1"`)
+	if err != nil {
+		t.Fatalf("RoundTrip() over the budget with a matching If-None-Match error = %v", err)
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("RoundTrip() over the budget with a matching If-None-Match status = %d, want %d", resp.StatusCode, http.StatusNotModified)
+	}
+
+	// Over budget, otherwise rejected
+	_, err = roundTrip("noisy", "/repos/foo/bar")
+	var bErr *BudgetError
+	if !errors.As(err, &bErr) {
+		t.Fatalf("RoundTrip() over the budget error = %v, want *BudgetError", err)
+	}
+	if want := (BudgetError{Caller: "noisy", Budget: budget, Reset: start.Add(time.Minute)}); *bErr != want {
+		t.Errorf("RoundTrip() error = %+v, want %+v", *bErr, want)
+	}
+
+	want := []CallerUsage{
+		{Caller: "noisy", Requests: 2, NotModified: 1, Stale: 2, Rejected: 1, WindowStart: start, WindowRequests: 2},
+		{Caller: "quiet", Requests: 1, WindowStart: start, WindowRequests: 1},
+	}
+	if got := tr.Usage(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Usage() = %+v, want %+v", got, want)
+	}
+
+	// The budget is replenished in the next window
+	now = now.Add(time.Minute)
+	if _, err := roundTrip("noisy", "/repos/foo/bar"); err != nil {
+		t.Fatalf("RoundTrip() in the next window error = %v", err)
+	}
+	if got := tr.Usage()[0]; got.WindowStart != now || got.WindowRequests != 1 || got.Requests != 3 {
+		t.Errorf("Usage() in the next window = %+v", got)
+	}
+}
+
+func TestTransport_RoundTrip_BudgetRange(t *testing.T) {
+	upstream := 0
+	tr := New(Options{
+		Storage: &mockStorage{},
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				upstream++
+				return &http.Response{StatusCode: http.StatusPartialContent, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("cont"))}, nil
+			},
+		},
+		Budgets: map[string]Budget{"noisy": {Requests: 1}},
+	})
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { return now }
+
+	// A "Range" request is not cacheable, but it still counts against the rate limit (and the budget)
+	var errs []error
+	for range 3 {
+		req, err := http.NewRequestWithContext(WithCaller(context.Background(), "noisy"), http.MethodGet, "https://api.github.com/repos/foo/bar/tarball/main", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Range", "bytes=0-3")
+		resp, err := tr.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		errs = append(errs, err)
+	}
+	var bErr *BudgetError
+	if errs[0] != nil || !errors.As(errs[1], &bErr) || !errors.As(errs[2], &bErr) {
+		t.Errorf("RoundTrip() errors = %v, want nil then *BudgetError", errs)
+	}
+	if upstream != 1 {
+		t.Errorf("RoundTrip() made %d upstream requests, want 1", upstream)
+	}
+	if got, want := tr.Usage(), []CallerUsage{{Caller: "noisy", Requests: 1, Rejected: 2, WindowStart: now, WindowRequests: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Usage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBudgetError_Error(t *testing.T) {
+	err := &BudgetError{Caller: "noisy", Budget: Budget{Requests: 100}}
+	if got, want := err.Error(), `caller "noisy" exhausted its budget of 100 upstream requests`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	err.Reset = time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	if got, want := err.Error(), `caller "noisy" exhausted its budget of 100 upstream requests until 2025-02-01T12:00:00Z`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
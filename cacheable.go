@@ -2,17 +2,27 @@ package ghtransport
 
 import "net/http"
 
-// cacheable determines if a GitHub REST API request will likely be cacheable.
+// cacheable determines if a GitHub REST API request will likely be cacheable, per its Route.
 // If not cacheable, it also returns the RFC 9211 forwarding reason ("method" or "bypass").
-func cacheable(req *http.Request) (bool, string) {
+func (t *Transport) cacheable(req *http.Request) (bool, string) {
 	if req.Method != "GET" && req.Method != "HEAD" {
 		return false, "method"
 	}
 	if req.Header.Get("Range") != "" {
 		return false, "bypass"
 	}
-	if req.URL.Path == "/rate_limit" || req.URL.Path == "/api/v3/rate_limit" {
+	if t.bypassed(req) {
 		return false, "bypass"
 	}
 	return true, ""
 }
+
+// bypassed reports if the request bypasses the cache per its Route. The free "/rate_limit" always does, even if
+// a custom table omits it. Unlike other requests forwarded as-is (ex: a "Range" request), these are neither
+// paced nor budgeted.
+func (t *Transport) bypassed(req *http.Request) bool {
+	if req.URL.Path == "/rate_limit" || req.URL.Path == "/api/v3/rate_limit" {
+		return true
+	}
+	return t.route(req).Bypass
+}
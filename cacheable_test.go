@@ -9,6 +9,7 @@ import (
 func Test_cacheable(t *testing.T) {
 	tests := map[string]struct {
 		Request     *http.Request
+		Routes      []Route
 		Expected    bool
 		ExpectedFwd string
 	}{
@@ -121,10 +122,36 @@ func Test_cacheable(t *testing.T) {
 			Expected:    false,
 			ExpectedFwd: "bypass",
 		},
+		"custom_routes_rate_limit": {
+			Request: &http.Request{
+				Method: "GET",
+				URL: &url.URL{
+					Scheme: "https",
+					Host:   "api.github.com",
+					Path:   "/rate_limit",
+				},
+			},
+			Routes:      []Route{{Path: "/orgs/{org}/audit-log", Bypass: true}},
+			Expected:    false,
+			ExpectedFwd: "bypass",
+		},
+		"custom_routes_bypass": {
+			Request: &http.Request{
+				Method: "GET",
+				URL: &url.URL{
+					Scheme: "https",
+					Host:   "api.github.com",
+					Path:   "/orgs/foo/audit-log",
+				},
+			},
+			Routes:      []Route{{Path: "/orgs/{org}/audit-log", Bypass: true}},
+			Expected:    false,
+			ExpectedFwd: "bypass",
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			got, fwd := cacheable(test.Request)
+			got, fwd := New(Options{Routes: test.Routes}).cacheable(test.Request)
 			if got != test.Expected {
 				t.Errorf("Cacheable(%v) = %v, want %v", test.Request, got, test.Expected)
 			}
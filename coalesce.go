@@ -0,0 +1,111 @@
+package ghtransport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// flights tracks the in-flight coalesced requests, keyed by coalesceKey.
+type flights struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is a single in-flight coalesced request, the response body is buffered so it can be shared.
+type flight struct {
+	done chan struct{}
+	resp *http.Response
+	body []byte
+	info CacheInfo
+	err  error
+}
+
+// coalesceKey identifies requests which are guaranteed to receive an identical response. The caller's own
+// validators are part of it, as they decide whether the response is a 304 Not Modified, as is the caller
+// identity (see WithCaller), as its Budget decides whether the request is rejected.
+func (t *Transport) coalesceKey(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte(' ')
+	b.WriteString(req.URL.String())
+	for _, header := range slices.Concat(t.opts.VaryHeaders, []string{"If-None-Match", "If-Modified-Since"}) {
+		vals := req.Header.Values(header)
+		if header == "Authorization" && len(vals) > 0 {
+			vals = []string{HashToken(vals[0])} // Don't keep the raw authentication token around
+		}
+		for _, val := range vals {
+			b.WriteByte('\n')
+			b.WriteString(header)
+			b.WriteString(": ")
+			b.WriteString(val)
+		}
+	}
+	if caller := callerFrom(req.Context()); caller != "" {
+		b.WriteString("\nCaller: ")
+		b.WriteString(caller)
+	}
+	return b.String()
+}
+
+// coalesce performs req via roundTrip, unless an identical request is already in-flight in which case
+// its response is shared. Each caller receives an independent copy of the response (and Body).
+func (t *Transport) coalesce(req *http.Request) (*http.Response, error) {
+	key := t.coalesceKey(req)
+
+	t.flights.mu.Lock()
+	f, collapsed := t.flights.calls[key]
+	if !collapsed {
+		if t.flights.calls == nil {
+			t.flights.calls = make(map[string]*flight)
+		}
+		f = &flight{done: make(chan struct{})}
+		t.flights.calls[key] = f
+
+		// The shared request must not be canceled if the first caller gives up waiting for it
+		go func() {
+			defer close(f.done)
+			ctx, info := withCacheInfo(context.WithoutCancel(req.Context()))
+			defer func() { f.info = *info }()
+			f.resp, f.err = t.roundTrip(req.Clone(ctx))
+			if f.err == nil {
+				f.body, f.err = io.ReadAll(f.resp.Body)
+				_ = f.resp.Body.Close()
+				if f.err != nil {
+					f.err = fmt.Errorf("(*http.Response).Body.Read failed: %w", f.err)
+				}
+			}
+			t.flights.mu.Lock()
+			delete(t.flights.calls, key)
+			t.flights.mu.Unlock()
+		}()
+	}
+	t.flights.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	if info := cacheInfoFrom(req.Context()); info != nil {
+		*info = f.info
+	}
+	resp := *f.resp
+	resp.Header = f.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(f.body))
+	resp.ContentLength = int64(len(f.body))
+	resp.Request = req
+	if collapsed {
+		resp.Header.Set("Cache-Status", resp.Header.Get("Cache-Status")+"; collapsed")
+	}
+	return &resp, nil
+}
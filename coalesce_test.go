@@ -0,0 +1,127 @@
+package ghtransport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// waitingContext is a context.Context that reports on waiting when its Done channel is first requested, which
+// a coalesced request only does once it is waiting for the in-flight request.
+type waitingContext struct {
+	context.Context
+	once    sync.Once
+	waiting chan<- struct{}
+}
+
+func (ctx *waitingContext) Done() <-chan struct{} {
+	ctx.once.Do(func() { ctx.waiting <- struct{}{} })
+	return ctx.Context.Done()
+}
+
+func TestTransport_RoundTrip_Coalesce(t *testing.T) {
+	const callers = 10
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	tr := New(Options{
+		Storage: &mockStorage{},
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				calls.Add(1)
+				<-release
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Etag": []string{`"tag1"`}},
+					Body:       io.NopCloser(strings.NewReader("content")),
+				}, nil
+			},
+		},
+		Coalesce: true,
+	})
+
+	var wg sync.WaitGroup
+	statuses := make(chan string, callers)
+	waiting := make(chan struct{}, callers)
+	for range callers {
+		wg.Go(func() {
+			ctx := &waitingContext{Context: context.Background(), waiting: waiting}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+			if err != nil {
+				t.Errorf("failed to create request: %v", err)
+				return
+			}
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Errorf("RoundTrip() error = %v", err)
+				return
+			}
+			defer resp.Body.Close()
+			if body, _ := io.ReadAll(resp.Body); string(body) != "content" {
+				t.Errorf("RoundTrip() body = %q, want %q", body, "content")
+			}
+			statuses <- resp.Header.Get("Cache-Status")
+		})
+	}
+
+	// Wait for every caller to join the in-flight request before releasing it
+	for range callers {
+		<-waiting
+	}
+	close(release)
+	wg.Wait()
+	close(statuses)
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("upstream RoundTrip called %d times, want 1", got)
+	}
+	var collapsed int
+	for status := range statuses {
+		if strings.Contains(status, "; collapsed") {
+			collapsed++
+		}
+	}
+	if collapsed != callers-1 {
+		t.Errorf("%d responses were collapsed, want %d", collapsed, callers-1)
+	}
+}
+
+func TestTransport_coalesceKey(t *testing.T) {
+	tr := NewTransport(nil, nil)
+	newRequest := func(method, authorization string) *http.Request {
+		req, err := http.NewRequest(method, "https://api.github.com/repos/foo/bar", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", authorization)
+		return req
+	}
+	base := tr.coalesceKey(newRequest(http.MethodGet, "Bearer hunter2"))
+	if strings.Contains(base, "hunter2") {
+		t.Errorf("coalesceKey() = %q, contains the raw token", base)
+	}
+	if got := tr.coalesceKey(newRequest(http.MethodGet, "Bearer hunter2")); got != base {
+		t.Errorf("coalesceKey() = %q, want %q", got, base)
+	}
+	if got := tr.coalesceKey(newRequest(http.MethodHead, "Bearer hunter2")); got == base {
+		t.Errorf("coalesceKey() for HEAD = %q, want != %q", got, base)
+	}
+	if got := tr.coalesceKey(newRequest(http.MethodGet, "Bearer hunter3")); got == base {
+		t.Errorf("coalesceKey() for other token = %q, want != %q", got, base)
+	}
+	for header, val := range map[string]string{"If-None-Match": `"tag1"`, "If-Modified-Since": "Sat, 01 Feb 2025 12:00:00 GMT"} {
+		req := newRequest(http.MethodGet, "Bearer hunter2")
+		req.Header.Set(header, val)
+		if got := tr.coalesceKey(req); got == base {
+			t.Errorf("coalesceKey() with %s = %q, want != %q", header, got, base)
+		}
+	}
+	req := newRequest(http.MethodGet, "Bearer hunter2")
+	if got := tr.coalesceKey(req.WithContext(WithCaller(req.Context(), "noisy"))); got == base {
+		t.Errorf("coalesceKey() with a caller = %q, want != %q", got, base)
+	}
+}
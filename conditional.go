@@ -7,25 +7,61 @@ import (
 	"io"
 	"net/http"
 	"slices"
+	"strings"
 )
 
-// addConditionalHeaders injects the conditional headers into the HTTP request if a cached response is available.
-func addConditionalHeaders(req *http.Request, cached *http.Response) error {
-	// If we have no cached response, speculatively guess the ETag for an empty `[]` response body
-	// This allows list endpoints that return no results to still benefit from a 304 Not Modified
-	if cached == nil {
-		h := Hash(req.Header, nil)
-		if _, err := h.Write([]byte("[]")); err != nil {
-			return fmt.Errorf("(hash.Hash).Write failed: %w", err)
+// candidate is a response that may be returned if the upstream response is 304 Not Modified, identified
+// by the ETag it is expected to have for the request. It is either a cached response or a speculative guess.
+type candidate struct {
+	etag        string
+	cached      *http.Response
+	speculation *Speculation
+}
+
+// addConditionalHeaders injects the conditional headers into the HTTP request, listing the expected ETag of
+// every candidate response in If-None-Match: each of the cached responses (if any) and the speculative guesses.
+func (t *Transport) addConditionalHeaders(req *http.Request, cached ...*http.Response) ([]candidate, error) {
+	var candidates []candidate
+	for _, resp := range cached {
+		if resp == nil {
+			continue
+		}
+		etag, err := t.expectedETag(req, resp)
+		if err != nil {
+			return nil, err
 		}
-		req.Header.Set("If-None-Match", `"`+hex.EncodeToString(h.Sum(nil))+`"`)
-		return nil
+		candidates = append(candidates, candidate{etag: etag, cached: resp})
 	}
 
+	// Speculatively guess the ETag for an "empty" response body (ex: `[]`)
+	// This allows endpoints that return no results to still benefit from a 304 Not Modified
+	for _, spec := range t.speculations(req) {
+		h := newHash(t.opts.VaryHeaders, req.Header, nil)
+		if _, err := h.Write([]byte(spec.Body)); err != nil {
+			return nil, fmt.Errorf("(hash.Hash).Write failed: %w", err)
+		}
+		candidates = append(candidates, candidate{etag: `"` + hex.EncodeToString(h.Sum(nil)) + `"`, speculation: spec})
+	}
+
+	etags := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if !slices.Contains(etags, c.etag) {
+			etags = append(etags, c.etag)
+		}
+	}
+	if len(etags) == 0 {
+		req.Header.Del("If-None-Match")
+	} else {
+		req.Header.Set("If-None-Match", strings.Join(etags, ", "))
+	}
+	return candidates, nil
+}
+
+// expectedETag calculates the ETag the cached response would have if it were returned for the request.
+func (t *Transport) expectedETag(req *http.Request, cached *http.Response) (string, error) {
 	// If the Vary headers are all identical to the cached values, we can use the cached ETag directly
-	if identicalVary(req, cached) {
-		req.Header.Set("If-None-Match", cached.Header.Get("Etag"))
-		return nil
+	if t.identicalVary(req, cached) {
+		return cached.Header.Get("Etag"), nil
 	}
 
 	// We'll have to consume the cached response body into memory to calculate the ETag
@@ -34,20 +70,106 @@ func addConditionalHeaders(req *http.Request, cached *http.Response) error {
 		buf.Grow(int(cached.ContentLength))
 	}
 	if _, err := buf.ReadFrom(cached.Body); err != nil {
-		return fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
+		return "", fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
 	}
 	if err := cached.Body.Close(); err != nil {
-		return fmt.Errorf("(*http.Response).Body.Close failed: %w", err)
+		return "", fmt.Errorf("(*http.Response).Body.Close failed: %w", err)
 	}
 	cached.Body = io.NopCloser(&buf)
 	cached.ContentLength = int64(buf.Len())
 
 	// Calculate the _expected_ ETag from the _input_ headers but the cached body
-	h := Hash(req.Header, slices.Collect(parseVary(cached.Header)))
+	h := newHash(t.opts.VaryHeaders, req.Header, slices.Collect(parseVary(cached.Header)))
 	if _, err := h.Write(buf.Bytes()); err != nil {
-		return fmt.Errorf("(hash.Hash).Write failed: %w", err)
+		return "", fmt.Errorf("(hash.Hash).Write failed: %w", err)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`, nil
+}
+
+// matchCandidate returns the candidate identified by the ETag of a 304 Not Modified response. If the response
+// has no ETag or it matches none of the candidates, ok is false.
+func matchCandidate(candidates []candidate, etag string) (_ candidate, ok bool) {
+	if etag == "" {
+		return candidate{}, false
+	}
+	for _, c := range candidates {
+		if weakETag(c.etag) == weakETag(etag) {
+			return c, true
+		}
+	}
+	return candidate{}, false
+}
+
+// validators are the conditional request headers supplied by the caller (as opposed to the transport).
+type validators struct {
+	ifNoneMatch     []string
+	ifModifiedSince string
+}
+
+// takeValidators removes the caller's conditional request headers, returning them.
+func takeValidators(headers http.Header) validators {
+	var v validators
+	for _, val := range headers.Values("If-None-Match") {
+		for etag := range strings.SplitSeq(val, ",") {
+			if etag = strings.TrimSpace(etag); etag != "" {
+				v.ifNoneMatch = append(v.ifNoneMatch, etag)
+			}
+		}
 	}
-	req.Header.Set("If-None-Match", `"`+hex.EncodeToString(h.Sum(nil))+`"`)
+	v.ifModifiedSince = headers.Get("If-Modified-Since")
+	headers.Del("If-None-Match")
+	headers.Del("If-Modified-Since")
+	return v
+}
+
+// empty reports if the caller supplied no validators.
+func (v validators) empty() bool {
+	return len(v.ifNoneMatch) == 0 && v.ifModifiedSince == ""
+}
+
+// match evaluates the caller's validators against the response, per RFC 9110 13.2.2. Any ETag the response
+// is known by counts, such as the ETag it was expected to have and the ETag it was stored with.
+func (v validators) match(resp *http.Response, etags ...string) bool {
+	if len(v.ifNoneMatch) > 0 {
+		etags = append(etags, resp.Header.Get("Etag"))
+		for _, want := range v.ifNoneMatch {
+			if want == "*" {
+				return true
+			}
+			for _, etag := range etags {
+				if etag != "" && weakETag(etag) == weakETag(want) {
+					return true
+				}
+			}
+		}
+		// If-Modified-Since must be ignored when If-None-Match is present
+		return false
+	}
+	if v.ifModifiedSince == "" {
+		return false
+	}
+	since, err := http.ParseTime(v.ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(since)
+}
+
+// weakETag strips the weakness indicator from an ETag, for the weak comparison per RFC 9110 8.8.3.2.
+func weakETag(etag string) string {
+	return strings.TrimPrefix(etag, "W/")
+}
 
-	return nil
+// notModified converts a 200 OK response into a 304 Not Modified response, discarding the body.
+func notModified(resp *http.Response) {
+	discardResponse(resp)
+	resp.StatusCode = http.StatusNotModified
+	resp.Status = http.StatusText(http.StatusNotModified)
+	resp.Body = io.NopCloser(strings.NewReader(""))
+	resp.ContentLength = 0
+	resp.Header.Del("Content-Length")
 }
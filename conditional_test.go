@@ -80,11 +80,16 @@ func Test_addConditionalHeaders(t *testing.T) {
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			if err := addConditionalHeaders(test.Request, test.Cached); err != nil {
+			candidates, err := NewTransport(nil, nil).addConditionalHeaders(test.Request, test.Cached)
+			if err != nil {
 				t.Fatalf("addConditionalHeaders failed: %v", err)
 			}
-			if inm := test.Request.Header.Get("If-None-Match"); inm != test.Expected {
-				t.Errorf("addConditionalHeaders request header = %q, want %q", inm, test.Expected)
+			// The first candidate is the cached response (if any), the speculative guess is always last
+			if candidates[0].etag != test.Expected {
+				t.Errorf("addConditionalHeaders candidate = %q, want %q", candidates[0].etag, test.Expected)
+			}
+			if inm := test.Request.Header.Get("If-None-Match"); !strings.HasPrefix(inm, test.Expected) {
+				t.Errorf("addConditionalHeaders request header = %q, want prefix %q", inm, test.Expected)
 			}
 			if test.Cached != nil && test.Cached.Body == nil {
 				t.Fatalf("addConditionalHeaders cached body is nil")
@@ -93,6 +98,61 @@ func Test_addConditionalHeaders(t *testing.T) {
 	}
 }
 
+func TestAddConditionalHeaders_MultipleCandidates(t *testing.T) {
+	req := &http.Request{
+		Header: http.Header{
+			"Authorization": []string{"Bearer hunter1"},
+		},
+	}
+	first := &http.Response{
+		Header: http.Header{
+			"Etag":                       []string{`"first"`},
+			VaryPrefix + "Authorization": []string{"Bearer hunter1"},
+		},
+		Body: io.NopCloser(strings.NewReader("first")),
+	}
+	second := &http.Response{
+		Header: http.Header{
+			"Etag":                       []string{`"second"`},
+			VaryPrefix + "Authorization": []string{"Bearer hunter1"},
+		},
+		Body: io.NopCloser(strings.NewReader("second")),
+	}
+	candidates, err := NewTransport(nil, nil).addConditionalHeaders(req, first, nil, second)
+	if err != nil {
+		t.Fatalf("addConditionalHeaders failed: %v", err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("addConditionalHeaders returned %d candidates, want 3", len(candidates))
+	}
+	speculative := candidates[2].etag
+	if inm, want := req.Header.Get("If-None-Match"), `"first", "second", `+speculative; inm != want {
+		t.Errorf("addConditionalHeaders request header = %q, want %q", inm, want)
+	}
+
+	tests := map[string]struct {
+		ETag     string
+		Expected *http.Response
+		OK       bool
+	}{
+		"empty":       {ETag: "", Expected: nil, OK: false},
+		"second":      {ETag: `W/"second"`, Expected: second, OK: true},
+		"speculative": {ETag: speculative, Expected: nil, OK: true},
+		"unknown":     {ETag: `"unknown"`, Expected: nil, OK: false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, ok := matchCandidate(candidates, test.ETag)
+			if ok != test.OK {
+				t.Fatalf("matchCandidate ok = %v, want %v", ok, test.OK)
+			}
+			if c.cached != test.Expected {
+				t.Errorf("matchCandidate cached = %v, want %v", c.cached, test.Expected)
+			}
+		})
+	}
+}
+
 // errCloser wraps an io.Reader with a Close method that returns a fixed error.
 type errCloser struct {
 	io.Reader
@@ -114,7 +174,7 @@ func TestAddConditionalHeaders_BodyReadError(t *testing.T) {
 		},
 		Body: io.NopCloser(iotest.ErrReader(errors.New("read failed"))),
 	}
-	err := addConditionalHeaders(req, cached)
+	_, err := NewTransport(nil, nil).addConditionalHeaders(req, cached)
 	if err == nil {
 		t.Fatal("expected addConditionalHeaders to fail when the cached body fails to read")
 	}
@@ -133,8 +193,73 @@ func TestAddConditionalHeaders_BodyCloseError(t *testing.T) {
 		},
 		Body: &errCloser{Reader: strings.NewReader("hello world"), closeErr: errors.New("close failed")},
 	}
-	err := addConditionalHeaders(req, cached)
+	_, err := NewTransport(nil, nil).addConditionalHeaders(req, cached)
 	if err == nil {
 		t.Fatal("expected addConditionalHeaders to fail when the cached body fails to close")
 	}
 }
+
+func Test_validators_match(t *testing.T) {
+	resp := &http.Response{
+		Header: http.Header{
+			"Etag":          []string{`W/"live"`},
+			"Last-Modified": []string{"Sat, 01 Feb 2025 12:00:00 GMT"},
+		},
+	}
+	tests := map[string]struct {
+		Headers  http.Header
+		Expected bool
+	}{
+		"none": {
+			Headers:  http.Header{},
+			Expected: false,
+		},
+		"live": {
+			Headers:  http.Header{"If-None-Match": []string{`"live"`}},
+			Expected: true,
+		},
+		"stored": {
+			Headers:  http.Header{"If-None-Match": []string{`"other", W/"stored"`}},
+			Expected: true,
+		},
+		"sent": {
+			Headers:  http.Header{"If-None-Match": []string{`"sent"`}},
+			Expected: true,
+		},
+		"wildcard": {
+			Headers:  http.Header{"If-None-Match": []string{`*`}},
+			Expected: true,
+		},
+		"mismatch": {
+			Headers:  http.Header{"If-None-Match": []string{`"other"`}},
+			Expected: false,
+		},
+		"modified_since_after": {
+			Headers:  http.Header{"If-Modified-Since": []string{"Sun, 02 Feb 2025 12:00:00 GMT"}},
+			Expected: true,
+		},
+		"modified_since_before": {
+			Headers:  http.Header{"If-Modified-Since": []string{"Fri, 31 Jan 2025 12:00:00 GMT"}},
+			Expected: false,
+		},
+		"modified_since_ignored": {
+			// If-Modified-Since must be ignored when If-None-Match is present
+			Headers: http.Header{
+				"If-None-Match":     []string{`"other"`},
+				"If-Modified-Since": []string{"Sun, 02 Feb 2025 12:00:00 GMT"},
+			},
+			Expected: false,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := takeValidators(test.Headers)
+			if len(test.Headers) != 0 {
+				t.Errorf("takeValidators left headers %v", test.Headers)
+			}
+			if got := v.match(resp, `"sent"`, `"stored"`); got != test.Expected {
+				t.Errorf("(validators).match() = %v, want %v", got, test.Expected)
+			}
+		})
+	}
+}
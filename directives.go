@@ -0,0 +1,84 @@
+package ghtransport
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestDirectives are the RFC 9111 request cache directives supported by the Transport. They are taken from
+// the "Cache-Control" request header (which is removed before the request is sent upstream) and/or the
+// context.Context of the request (see WithDirectives).
+type RequestDirectives struct {
+	// NoCache never serves a stored response without revalidating it upstream, not even a fresh one.
+	NoCache bool
+	// NoStore never stores the response.
+	NoStore bool
+	// MaxStale accepts a stored response that has exceeded its freshness lifetime by up to MaxStale, without
+	// revalidating it. A "max-stale" directive without a value accepts any staleness (math.MaxInt64).
+	MaxStale time.Duration
+	// OnlyIfCached only answers from the Storage, see Options.OnlyIfCached.
+	OnlyIfCached bool
+}
+
+// merge combines the directives, as if both were supplied.
+func (d RequestDirectives) merge(other RequestDirectives) RequestDirectives {
+	return RequestDirectives{
+		NoCache:      d.NoCache || other.NoCache,
+		NoStore:      d.NoStore || other.NoStore,
+		MaxStale:     max(d.MaxStale, other.MaxStale),
+		OnlyIfCached: d.OnlyIfCached || other.OnlyIfCached,
+	}
+}
+
+// directivesKey is the context.Context key of WithDirectives.
+type directivesKey struct{}
+
+// WithDirectives returns a copy of the context carrying the request cache directives, combined with any
+// directives the context already carries.
+func WithDirectives(ctx context.Context, directives RequestDirectives) context.Context {
+	return context.WithValue(ctx, directivesKey{}, directivesFrom(ctx).merge(directives))
+}
+
+// directivesFrom returns the request cache directives carried by the context.
+func directivesFrom(ctx context.Context) RequestDirectives {
+	directives, _ := ctx.Value(directivesKey{}).(RequestDirectives)
+	return directives
+}
+
+// parseDirectives parses the request cache directives of the "Cache-Control" request header.
+func parseDirectives(headers http.Header) RequestDirectives {
+	var directives RequestDirectives
+	for name, val := range cacheControl(headers) {
+		switch name {
+		case "no-cache":
+			directives.NoCache = true
+		case "no-store":
+			directives.NoStore = true
+		case "max-stale":
+			if val == "" {
+				directives.MaxStale = math.MaxInt64
+			} else if seconds, err := strconv.ParseInt(val, 10, 64); err == nil && seconds > 0 {
+				directives.MaxStale = time.Duration(min(seconds, math.MaxInt64/int64(time.Second))) * time.Second
+			}
+		case "only-if-cached":
+			directives.OnlyIfCached = true
+		}
+	}
+	return directives
+}
+
+// withDirectives moves the directives of the "Cache-Control" request header (if any) into the context of
+// the request, such that they apply to the request but are not sent upstream.
+func withDirectives(req *http.Request) *http.Request {
+	if _, ok := req.Header["Cache-Control"]; !ok {
+		return req
+	}
+	directives := parseDirectives(req.Header)
+	// Per the http.RoundTripper contract, we cannot modify the request in-place, we need to shallow clone it
+	req = req.Clone(WithDirectives(req.Context(), directives))
+	req.Header.Del("Cache-Control")
+	return req
+}
@@ -0,0 +1,177 @@
+package ghtransport
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseDirectives(t *testing.T) {
+	tests := map[string]struct {
+		CacheControl string
+		Expected     RequestDirectives
+	}{
+		"empty":          {CacheControl: "", Expected: RequestDirectives{}},
+		"no-cache":       {CacheControl: "no-cache", Expected: RequestDirectives{NoCache: true}},
+		"no-store":       {CacheControl: "No-Store", Expected: RequestDirectives{NoStore: true}},
+		"max-stale":      {CacheControl: "max-stale=600", Expected: RequestDirectives{MaxStale: 10 * time.Minute}},
+		"max-stale_any":  {CacheControl: "max-stale", Expected: RequestDirectives{MaxStale: math.MaxInt64}},
+		"max-stale_huge": {CacheControl: "max-stale=99999999999999999", Expected: RequestDirectives{MaxStale: math.MaxInt64 / time.Second * time.Second}},
+		"only-if-cached": {CacheControl: "only-if-cached", Expected: RequestDirectives{OnlyIfCached: true}},
+		"combined":       {CacheControl: "no-cache, no-store, max-age=0", Expected: RequestDirectives{NoCache: true, NoStore: true}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := parseDirectives(http.Header{"Cache-Control": []string{test.CacheControl}}); got != test.Expected {
+				t.Errorf("parseDirectives(%q) = %+v, want %+v", test.CacheControl, got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestWithDirectives(t *testing.T) {
+	ctx := WithDirectives(context.Background(), RequestDirectives{NoCache: true})
+	ctx = WithDirectives(ctx, RequestDirectives{MaxStale: time.Minute})
+	ctx = WithOnlyIfCached(ctx)
+	want := RequestDirectives{NoCache: true, MaxStale: time.Minute, OnlyIfCached: true}
+	if got := directivesFrom(ctx); got != want {
+		t.Errorf("directivesFrom() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTransport_RoundTrip_Directives(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		CacheControl    string
+		Directives      RequestDirectives
+		StoredAt        time.Time
+		WantUpstream    bool
+		WantStored      bool
+		WantStatusCode  int
+		WantCacheStatus string
+	}{
+		"fresh": {
+			StoredAt:        now.Add(-30 * time.Second),
+			WantUpstream:    false,
+			WantStatusCode:  http.StatusOK,
+			WantCacheStatus: cacheStatusHitTTL(CacheName, 30*time.Second),
+		},
+		"no-cache": {
+			CacheControl:    "no-cache",
+			StoredAt:        now.Add(-30 * time.Second),
+			WantUpstream:    true,
+			WantStored:      true,
+			WantStatusCode:  http.StatusOK,
+			WantCacheStatus: cacheStatusForward(CacheName, "stale", http.StatusOK, true),
+		},
+		"no-cache_context": {
+			Directives:      RequestDirectives{NoCache: true},
+			StoredAt:        now.Add(-30 * time.Second),
+			WantUpstream:    true,
+			WantStored:      true,
+			WantStatusCode:  http.StatusOK,
+			WantCacheStatus: cacheStatusForward(CacheName, "stale", http.StatusOK, true),
+		},
+		"no-store": {
+			CacheControl:    "no-store",
+			StoredAt:        now.Add(-10 * time.Minute),
+			WantUpstream:    true,
+			WantStored:      false,
+			WantStatusCode:  http.StatusOK,
+			WantCacheStatus: cacheStatusForward(CacheName, "stale", http.StatusOK, false),
+		},
+		"max-stale": {
+			CacheControl:    "max-stale=600",
+			StoredAt:        now.Add(-5 * time.Minute),
+			WantUpstream:    false,
+			WantStatusCode:  http.StatusOK,
+			WantCacheStatus: cacheStatusHitTTL(CacheName, -4*time.Minute),
+		},
+		"max-stale_exceeded": {
+			Directives:      RequestDirectives{MaxStale: time.Minute},
+			StoredAt:        now.Add(-5 * time.Minute),
+			WantUpstream:    true,
+			WantStored:      true,
+			WantStatusCode:  http.StatusOK,
+			WantCacheStatus: cacheStatusForward(CacheName, "stale", http.StatusOK, true),
+		},
+		"only-if-cached": {
+			CacheControl:    "only-if-cached",
+			StoredAt:        now.Add(-5 * time.Minute),
+			WantUpstream:    false,
+			WantStatusCode:  http.StatusOK,
+			WantCacheStatus: cacheStatusHit(CacheName),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstream, stored := false, false
+			tr := New(Options{
+				Storage: &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Status:     "200 OK",
+							Header: http.Header{
+								"Etag":         []string{`"tag1"`},
+								StoredAtHeader: []string{test.StoredAt.Format(http.TimeFormat)},
+							},
+							Body: io.NopCloser(strings.NewReader("cached content")),
+						}, nil
+					},
+					putFunc: func(ctx context.Context, resp *http.Response) error {
+						stored = true
+						return nil
+					},
+				},
+				Parent: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						upstream = true
+						if cc := req.Header.Get("Cache-Control"); cc != "" {
+							t.Errorf("upstream Cache-Control = %q, want none", cc)
+						}
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Header:     http.Header{"Etag": []string{`"tag2"`}},
+							Body:       io.NopCloser(strings.NewReader("live content")),
+						}, nil
+					},
+				},
+				TTL: time.Minute,
+			})
+			tr.now = func() time.Time { return now }
+
+			req, err := http.NewRequestWithContext(WithDirectives(context.Background(), test.Directives), http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if test.CacheControl != "" {
+				req.Header.Set("Cache-Control", test.CacheControl)
+			}
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			resp.Body.Close()
+			if test.CacheControl != "" && req.Header.Get("Cache-Control") != test.CacheControl {
+				t.Errorf("RoundTrip() modified the request headers")
+			}
+			if upstream != test.WantUpstream {
+				t.Errorf("RoundTrip() upstream = %v, want %v", upstream, test.WantUpstream)
+			}
+			if stored != test.WantStored {
+				t.Errorf("RoundTrip() stored = %v, want %v", stored, test.WantStored)
+			}
+			if resp.StatusCode != test.WantStatusCode {
+				t.Errorf("RoundTrip() status = %d, want %d", resp.StatusCode, test.WantStatusCode)
+			}
+			if got, want := resp.Header.Get("Cache-Status"), withOperation(test.WantCacheStatus); got != want {
+				t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package ghtransport
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StoredAtHeader is the response header recording when a response was stored, such that its freshness can
+// be determined. It is returned with every response served from the cache.
+const StoredAtHeader = "X-Cache-Stored-At"
+
+// ValidatedAtHeader is the response header recording when a stored response was last validated by a
+// 304 Not Modified, per RFC 9111 4.3.4. It is returned with every response served from the cache as well.
+const ValidatedAtHeader = "X-Cache-Validated-At"
+
+// cacheControl parses the "Cache-Control" header (comma-separated) into its directives, lowercasing the
+// names and unquoting the values.
+func cacheControl(headers http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, val := range headers.Values("Cache-Control") {
+		for directive := range strings.SplitSeq(val, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return directives
+}
+
+// validatedAt determines when the cached response was last known to be current: when it was last validated
+// (see ValidatedAtHeader) or stored (see StoredAtHeader), whichever is later, falling back to its "Date"
+// header for responses stored without either. If it cannot be determined, ok is false.
+func validatedAt(cached *http.Response) (_ time.Time, ok bool) {
+	var latest time.Time
+	for _, header := range []string{StoredAtHeader, ValidatedAtHeader} {
+		if at, err := http.ParseTime(cached.Header.Get(header)); err == nil && at.After(latest) {
+			latest = at
+		}
+	}
+	if !latest.IsZero() {
+		return latest, true
+	}
+	date, err := http.ParseTime(cached.Header.Get("Date"))
+	return date, err == nil
+}
+
+// validatedAge calculates how long ago the cached response was last known to be current, see validatedAt.
+func (t *Transport) validatedAge(cached *http.Response) (_ time.Duration, ok bool) {
+	at, ok := validatedAt(cached)
+	if !ok {
+		return 0, false
+	}
+	return max(t.now().Sub(at), 0), true
+}
+
+// lifetime determines the freshness lifetime of the cached response: the TTL of its route, else the
+// "max-age" of its "Cache-Control" header (if Options.MaxAge is set), else Options.TTL.
+func (t *Transport) lifetime(route Route, cached *http.Response) time.Duration {
+	if route.TTL > 0 {
+		return time.Duration(route.TTL)
+	}
+	if t.opts.MaxAge {
+		if val, ok := cacheControl(cached.Header)["max-age"]; ok {
+			if seconds, err := strconv.ParseInt(val, 10, 64); err == nil && seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return t.opts.TTL
+}
+
+// fresh reports if the cached response is within its freshness lifetime, or has exceeded it by less than
+// maxStale, returning how long it remains fresh (negative if it is stale, per RFC 9211's "ttl" parameter).
+func (t *Transport) fresh(route Route, cached *http.Response, maxStale time.Duration) (_ time.Duration, ok bool) {
+	lifetime := t.lifetime(route, cached)
+	if lifetime <= 0 && maxStale <= 0 {
+		return 0, false
+	}
+	age, ok := t.validatedAge(cached)
+	if !ok {
+		return 0, false
+	}
+	if stale := age - lifetime; stale >= 0 && stale >= maxStale {
+		return 0, false
+	}
+	return lifetime - age, true
+}
+
+// cacheStatusHitTTL builds the "Cache-Status" header value for a cache hit that remains fresh for ttl, per
+// RFC 9211's "ttl" parameter.
+func cacheStatusHitTTL(name string, ttl time.Duration) string {
+	return fmt.Sprintf("%s; ttl=%d", cacheStatusHit(name), int64(ttl/time.Second))
+}
+
+// freshResponse builds a response to req from a cached response that is fresh, so it needs no revalidation.
+// The caller's own conditional headers are evaluated against it.
+func (t *Transport) freshResponse(req *http.Request, cached *http.Response, cacheStatus string) *http.Response {
+	resp := t.cachedResponse(req, cached)
+	setCacheStatus(resp, cacheStatus, "HIT")
+	if resp.StatusCode == http.StatusOK && takeValidators(req.Header.Clone()).match(resp, cached.Header.Get("Etag")) {
+		notModified(resp)
+	}
+	return resp
+}
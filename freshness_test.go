@@ -0,0 +1,295 @@
+package ghtransport
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_cacheControl(t *testing.T) {
+	got := cacheControl(http.Header{"Cache-Control": []string{`private, Max-Age=60`, `s-maxage="30"`}})
+	want := map[string]string{"private": "", "max-age": "60", "s-maxage": "30"}
+	if len(got) != len(want) {
+		t.Fatalf("cacheControl() = %v, want %v", got, want)
+	}
+	for name, val := range want {
+		if got[name] != val {
+			t.Errorf("cacheControl()[%q] = %q, want %q", name, got[name], val)
+		}
+	}
+}
+
+func TestTransport_fresh(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		Options  Options
+		Route    Route
+		MaxStale time.Duration
+		Header   http.Header
+		Expected time.Duration
+		OK       bool
+	}{
+		"none": {
+			Header: http.Header{"Date": []string{now.Add(-time.Minute).Format(http.TimeFormat)}},
+			OK:     false,
+		},
+		"global": {
+			Options:  Options{TTL: time.Hour},
+			Header:   http.Header{"Date": []string{now.Add(-time.Minute).Format(http.TimeFormat)}},
+			Expected: 59 * time.Minute,
+			OK:       true,
+		},
+		"global_expired": {
+			Options: Options{TTL: time.Minute},
+			Header:  http.Header{"Date": []string{now.Add(-time.Hour).Format(http.TimeFormat)}},
+			OK:      false,
+		},
+		"stored_at": {
+			// The store time takes precedence over the (older) "Date" header
+			Options: Options{TTL: time.Hour},
+			Header: http.Header{
+				"Date":         []string{now.Add(-2 * time.Hour).Format(http.TimeFormat)},
+				StoredAtHeader: []string{now.Add(-time.Minute).Format(http.TimeFormat)},
+			},
+			Expected: 59 * time.Minute,
+			OK:       true,
+		},
+		"validated_at": {
+			// The last validation takes precedence over the (older) store time
+			Options: Options{TTL: time.Hour},
+			Header: http.Header{
+				StoredAtHeader:    []string{now.Add(-2 * time.Hour).Format(http.TimeFormat)},
+				ValidatedAtHeader: []string{now.Add(-time.Minute).Format(http.TimeFormat)},
+			},
+			Expected: 59 * time.Minute,
+			OK:       true,
+		},
+		"max_age": {
+			Options: Options{TTL: time.Hour, MaxAge: true},
+			Header: http.Header{
+				"Cache-Control": []string{"private, max-age=60"},
+				StoredAtHeader:  []string{now.Add(-10 * time.Second).Format(http.TimeFormat)},
+			},
+			Expected: 50 * time.Second,
+			OK:       true,
+		},
+		"max_age_ignored": {
+			Options: Options{TTL: time.Hour},
+			Header: http.Header{
+				"Cache-Control": []string{"private, max-age=60"},
+				StoredAtHeader:  []string{now.Add(-10 * time.Second).Format(http.TimeFormat)},
+			},
+			Expected: time.Hour - 10*time.Second,
+			OK:       true,
+		},
+		"route": {
+			Options: Options{TTL: time.Hour, MaxAge: true},
+			Route:   Route{TTL: Duration(2 * time.Minute)},
+			Header: http.Header{
+				"Cache-Control": []string{"private, max-age=60"},
+				StoredAtHeader:  []string{now.Add(-10 * time.Second).Format(http.TimeFormat)},
+			},
+			Expected: 110 * time.Second,
+			OK:       true,
+		},
+		"max_stale": {
+			Options:  Options{TTL: time.Minute},
+			MaxStale: 10 * time.Minute,
+			Header:   http.Header{StoredAtHeader: []string{now.Add(-5 * time.Minute).Format(http.TimeFormat)}},
+			Expected: -4 * time.Minute,
+			OK:       true,
+		},
+		"max_stale_exceeded": {
+			Options:  Options{TTL: time.Minute},
+			MaxStale: 10 * time.Minute,
+			Header:   http.Header{StoredAtHeader: []string{now.Add(-20 * time.Minute).Format(http.TimeFormat)}},
+			OK:       false,
+		},
+		"max_stale_without_ttl": {
+			MaxStale: math.MaxInt64,
+			Header:   http.Header{StoredAtHeader: []string{now.Add(-24 * time.Hour).Format(http.TimeFormat)}},
+			Expected: -24 * time.Hour,
+			OK:       true,
+		},
+		"unknown_age": {
+			Options: Options{TTL: time.Hour},
+			Header:  http.Header{},
+			OK:      false,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tr := New(test.Options)
+			tr.now = func() time.Time { return now }
+			ttl, ok := tr.fresh(test.Route, &http.Response{Header: test.Header}, test.MaxStale)
+			if ok != test.OK {
+				t.Fatalf("fresh() ok = %v, want %v", ok, test.OK)
+			}
+			if ttl != test.Expected {
+				t.Errorf("fresh() = %v, want %v", ttl, test.Expected)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_TTL(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	var stored *http.Response
+	upstream := 0
+	tr := New(Options{
+		Storage: &mockStorage{
+			getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				if stored == nil {
+					return nil, nil
+				}
+				resp := *stored
+				resp.Body = io.NopCloser(strings.NewReader("content"))
+				return &resp, nil
+			},
+			putFunc: func(ctx context.Context, resp *http.Response) error {
+				stored = resp
+				return nil
+			},
+		},
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				upstream++
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Etag": []string{`"tag1"`}},
+					Body:       io.NopCloser(strings.NewReader("content")),
+				}, nil
+			},
+		},
+		TTL: time.Minute,
+	})
+	tr.now = func() time.Time { return now }
+
+	roundTrip := func() *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	roundTrip()
+	if got := stored.Header.Get(StoredAtHeader); got != now.Format(http.TimeFormat) {
+		t.Errorf("stored %s = %q, want %q", StoredAtHeader, got, now.Format(http.TimeFormat))
+	}
+
+	now = now.Add(20 * time.Second)
+	resp := roundTrip()
+	if upstream != 1 {
+		t.Errorf("RoundTrip() within the TTL made %d upstream requests, want 1", upstream)
+	}
+	if got, want := resp.Header.Get("Cache-Status"), withOperation(cacheStatusHitTTL(CacheName, 40*time.Second)); got != want {
+		t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, want)
+	}
+	if got, want := resp.Header.Get(StoredAtHeader), now.Add(-20*time.Second).Format(http.TimeFormat); got != want {
+		t.Errorf("RoundTrip() %s = %q, want %q", StoredAtHeader, got, want)
+	}
+	if _, ok := resp.Header[StoredByHeader]; ok {
+		t.Errorf("RoundTrip() leaked the internal %s header", StoredByHeader)
+	}
+
+	now = now.Add(time.Minute)
+	roundTrip()
+	if upstream != 2 {
+		t.Errorf("RoundTrip() after the TTL made %d upstream requests, want 2", upstream)
+	}
+}
+
+func TestTransport_RoundTrip_TTL_Revalidated(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	var stored *http.Response
+	upstream := 0
+	tr := New(Options{
+		Storage: &mockStorage{
+			getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				if stored == nil {
+					return nil, nil
+				}
+				resp := *stored
+				resp.Body = io.NopCloser(strings.NewReader("content"))
+				return &resp, nil
+			},
+			putFunc: func(ctx context.Context, resp *http.Response) error {
+				stored = resp
+				return nil
+			},
+		},
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				upstream++
+				if strings.Contains(req.Header.Get("If-None-Match"), `"tag1"`) {
+					return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{"Etag": []string{`"tag1"`}}, Body: http.NoBody}, nil
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Etag": []string{`"tag1"`}},
+					Body:       io.NopCloser(strings.NewReader("content")),
+				}, nil
+			},
+		},
+		Speculations: []Speculation{},
+		TTL:          time.Minute,
+	})
+	tr.now = func() time.Time { return now }
+
+	roundTrip := func() *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		if body, _ := io.ReadAll(resp.Body); string(body) != "content" {
+			t.Errorf("RoundTrip() body = %q, want %q", body, "content")
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	stored0 := now
+	roundTrip()
+
+	// Once the TTL passed, the stored response is revalidated with a 304 Not Modified
+	now = now.Add(2 * time.Minute)
+	resp := roundTrip()
+	if upstream != 2 {
+		t.Errorf("RoundTrip() after the TTL made %d upstream requests, want 2", upstream)
+	}
+	if got, want := stored.Header.Get(ValidatedAtHeader), now.Format(http.TimeFormat); got != want {
+		t.Errorf("stored %s = %q, want %q", ValidatedAtHeader, got, want)
+	}
+	if got, want := stored.Header.Get(StoredAtHeader), stored0.Format(http.TimeFormat); got != want {
+		t.Errorf("stored %s = %q, want %q", StoredAtHeader, got, want)
+	}
+	if got, want := resp.Header.Get(ValidatedAtHeader), now.Format(http.TimeFormat); got != want {
+		t.Errorf("RoundTrip() %s = %q, want %q", ValidatedAtHeader, got, want)
+	}
+
+	// The validation made it fresh again
+	now = now.Add(time.Second)
+	resp = roundTrip()
+	if upstream != 2 {
+		t.Errorf("RoundTrip() after the revalidation made %d upstream requests, want 2", upstream)
+	}
+	if got, want := resp.Header.Get("Cache-Status"), withOperation(cacheStatusHitTTL(CacheName, 59*time.Second)); got != want {
+		t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, want)
+	}
+}
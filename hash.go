@@ -10,7 +10,8 @@ import (
 	"strings"
 )
 
-// VaryHeaders are the headers that are used to vary the cache key, this slice _must_ remain sorted.
+// VaryHeaders are the default headers that are used to vary the cache key, this slice _must_ remain sorted.
+// It may be overridden per Transport via Options.VaryHeaders.
 var VaryHeaders = []string{
 	"Accept",
 	"Authorization",
@@ -20,12 +21,17 @@ var VaryHeaders = []string{
 // Hash initializes a hash.Hash following the GitHub's internal ETag implementation.
 // The response body must be written to the hash before it can be used to calculate the ETag.
 func Hash(requestHeaders http.Header, vary []string) hash.Hash {
+	return newHash(VaryHeaders, requestHeaders, vary)
+}
+
+// newHash implements Hash for an explicit set of varyHeaders.
+func newHash(varyHeaders []string, requestHeaders http.Header, vary []string) hash.Hash {
 	h := sha256.New()
 	// Per RFC 9110 12.5.5, "Vary: *" means the representation may vary on unspecified
 	// request headers, so we conservatively include every header we know GitHub's ETag
 	// algorithm can use (the same as vary == nil), rather than none of them.
 	all := vary == nil || slices.Contains(vary, "*")
-	for _, headerName := range VaryHeaders {
+	for _, headerName := range varyHeaders {
 		if all || slices.Contains(vary, headerName) {
 			for _, headerValue := range requestHeaders.Values(headerName) {
 				h.Write(append([]byte(headerValue), ':'))
@@ -0,0 +1,72 @@
+package ghtransport
+
+import (
+	"net/http"
+	"strings"
+)
+
+// PrincipalHeaders are the response headers describing the principal (token) that made the request rather
+// than the requested resource, they must never be returned in response to a request by a different principal.
+// A trailing "*" matches any header with that prefix.
+var PrincipalHeaders = []string{
+	"Github-Authentication-Token-Expiration",
+	"Set-Cookie",
+	"X-Accepted-Oauth-Scopes",
+	"X-Github-Sso",
+	"X-Oauth-Client-Id",
+	"X-Oauth-Scopes",
+	"X-Ratelimit-*",
+}
+
+// HeaderPolicy determines which response headers are kept. The struct tags allow it to be loaded from JSON
+// or YAML, like a Route.
+type HeaderPolicy struct {
+	// Allow, if non-nil, lists the only headers that are kept.
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	// Deny lists the headers that are never kept, it takes precedence over Allow.
+	Deny []string `json:"deny,omitempty" yaml:"deny,omitempty"`
+}
+
+// DefaultHeaderPolicy is the default policy of both Options.PersistHeaders and Options.ReplayHeaders: every
+// header is kept except the PrincipalHeaders.
+var DefaultHeaderPolicy = HeaderPolicy{Deny: PrincipalHeaders}
+
+// isZero reports if the policy is unset (as opposed to an explicitly empty Allow or Deny).
+func (p HeaderPolicy) isZero() bool {
+	return p.Allow == nil && p.Deny == nil
+}
+
+// Allows reports if the policy keeps the header. Header names are matched case-insensitively and a trailing
+// "*" matches any header with that prefix (ex: "X-Ratelimit-*").
+func (p HeaderPolicy) Allows(key string) bool {
+	if matchHeader(p.Deny, key) {
+		return false
+	}
+	return p.Allow == nil || matchHeader(p.Allow, key)
+}
+
+// matchHeader reports if the header matches any of the header names (or prefixes).
+func matchHeader(patterns []string, key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(key, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// persistHeader reports if the response header is persisted by Storage.Put. The "Etag", "Vary" and
+// "Cache-Control" headers are always persisted as revalidation (and freshness) depends on them.
+func (t *Transport) persistHeader(key string) bool {
+	return key == "Etag" || key == "Vary" || key == "Cache-Control" || t.opts.PersistHeaders.Allows(key)
+}
+
+// replayHeader reports if the stored response header is returned when answering from the cache.
+func (t *Transport) replayHeader(key string) bool {
+	return !t.internalHeader(key) && t.opts.ReplayHeaders.Allows(key)
+}
@@ -0,0 +1,248 @@
+package ghtransport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHeaderPolicy_Allows(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Policy   HeaderPolicy
+		Header   string
+		Expected bool
+	}{
+		{
+			Name:     "zero",
+			Header:   "X-Oauth-Scopes",
+			Expected: true,
+		},
+		{
+			Name:     "default deny",
+			Policy:   DefaultHeaderPolicy,
+			Header:   "X-Oauth-Scopes",
+			Expected: false,
+		},
+		{
+			Name:     "default deny prefix",
+			Policy:   DefaultHeaderPolicy,
+			Header:   "X-Ratelimit-Remaining",
+			Expected: false,
+		},
+		{
+			Name:     "default non-canonical",
+			Policy:   DefaultHeaderPolicy,
+			Header:   "x-github-sso",
+			Expected: false,
+		},
+		{
+			Name:     "default allow",
+			Policy:   DefaultHeaderPolicy,
+			Header:   "Content-Type",
+			Expected: true,
+		},
+		{
+			Name:     "allow list",
+			Policy:   HeaderPolicy{Allow: []string{"Content-Type", "Link"}},
+			Header:   "Link",
+			Expected: true,
+		},
+		{
+			Name:     "not in allow list",
+			Policy:   HeaderPolicy{Allow: []string{"Content-Type", "Link"}},
+			Header:   "Server",
+			Expected: false,
+		},
+		{
+			Name:     "empty allow list",
+			Policy:   HeaderPolicy{Allow: []string{}},
+			Header:   "Content-Type",
+			Expected: false,
+		},
+		{
+			Name:     "deny takes precedence",
+			Policy:   HeaderPolicy{Allow: []string{"X-*"}, Deny: []string{"X-Oauth-*"}},
+			Header:   "X-Oauth-Scopes",
+			Expected: false,
+		},
+		{
+			Name:     "allow prefix",
+			Policy:   HeaderPolicy{Allow: []string{"X-*"}, Deny: []string{"X-Oauth-*"}},
+			Header:   "X-Github-Media-Type",
+			Expected: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if got := test.Policy.Allows(test.Header); got != test.Expected {
+				t.Errorf("Allows(%q) = %v, want %v", test.Header, got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_HeaderPolicy(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Persist  HeaderPolicy
+		Replay   HeaderPolicy
+		Cached   http.Header
+		Stored   []string
+		Dropped  []string
+		Replayed map[string]string
+	}{
+		{
+			Name: "defaults",
+			Cached: http.Header{
+				"X-Oauth-Scopes":          []string{"repo, admin:org"},
+				"X-Accepted-Oauth-Scopes": []string{"repo"},
+				"X-Ratelimit-Remaining":   []string{"4999"},
+				"Link":                    []string{`<https://api.github.com/repos/foo/bar?page=2>; rel="next"`},
+			},
+			Stored:  []string{"Etag", "Link"},
+			Dropped: []string{"X-Oauth-Scopes", "X-Accepted-Oauth-Scopes", "X-Ratelimit-Remaining"},
+			Replayed: map[string]string{
+				"Link":                  `<https://api.github.com/repos/foo/bar?page=2>; rel="next"`,
+				"X-Oauth-Scopes":        "repo",
+				"X-Ratelimit-Remaining": "4998",
+			},
+		},
+		{
+			Name:    "allow list",
+			Persist: HeaderPolicy{Allow: []string{"Content-Type"}},
+			Replay:  HeaderPolicy{Allow: []string{"Content-Type"}},
+			Cached: http.Header{
+				"Content-Type": []string{"application/json"},
+				"Link":         []string{`<https://api.github.com/repos/foo/bar?page=2>; rel="next"`},
+			},
+			Stored:  []string{"Etag", "Content-Type"},
+			Dropped: []string{"Link"},
+			Replayed: map[string]string{
+				"Content-Type":   "application/json",
+				"Link":           "",
+				"X-Oauth-Scopes": "repo",
+			},
+		},
+		{
+			Name:    "persist everything",
+			Persist: HeaderPolicy{Deny: []string{}},
+			Cached: http.Header{
+				"X-Oauth-Scopes": []string{"repo, admin:org"},
+			},
+			Stored: []string{"Etag", "X-Oauth-Scopes"},
+			Replayed: map[string]string{
+				// The live 304 header always wins, the stored one is not replayed
+				"X-Oauth-Scopes": "repo",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var stored *http.Response
+			tr := New(Options{
+				Storage: &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						if stored == nil {
+							return nil, nil
+						}
+						resp := *stored
+						resp.Body = io.NopCloser(strings.NewReader("content"))
+						return &resp, nil
+					},
+					putFunc: func(ctx context.Context, resp *http.Response) error {
+						stored = resp
+						return nil
+					},
+				},
+				Parent: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						if strings.Contains(req.Header.Get("If-None-Match"), `"tag1"`) {
+							return &http.Response{
+								StatusCode: http.StatusNotModified,
+								Header: http.Header{
+									"Etag":                  []string{`"tag1"`},
+									"X-Oauth-Scopes":        []string{"repo"},
+									"X-Ratelimit-Remaining": []string{"4998"},
+								},
+								Body: http.NoBody,
+							}, nil
+						}
+						header := test.Cached.Clone()
+						header.Set("Etag", `"tag1"`)
+						return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader("content"))}, nil
+					},
+				},
+				PersistHeaders: test.Persist,
+				ReplayHeaders:  test.Replay,
+			})
+
+			roundTrip := func() *http.Response {
+				t.Helper()
+				req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+				if err != nil {
+					t.Fatalf("failed to create request: %v", err)
+				}
+				resp, err := tr.RoundTrip(req)
+				if err != nil {
+					t.Fatalf("RoundTrip() error = %v", err)
+				}
+				resp.Body.Close()
+				return resp
+			}
+
+			roundTrip()
+			for _, key := range test.Stored {
+				if _, ok := stored.Header[key]; !ok {
+					t.Errorf("stored response is missing the %s header", key)
+				}
+			}
+			for _, key := range test.Dropped {
+				if _, ok := stored.Header[key]; ok {
+					t.Errorf("stored response has the %s header", key)
+				}
+			}
+
+			resp := roundTrip()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("RoundTrip() status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+			for key, want := range test.Replayed {
+				if got := resp.Header.Get(key); got != want {
+					t.Errorf("RoundTrip() %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTransport_cachedResponse_ReplayHeaders(t *testing.T) {
+	tr := New(Options{})
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	// Stored before the PersistHeaders policy dropped the principal-specific headers
+	cached := &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Etag":                                   []string{`"tag1"`},
+			"Content-Type":                           []string{"application/json"},
+			"X-Oauth-Scopes":                         []string{"repo, admin:org"},
+			"Github-Authentication-Token-Expiration": []string{"2025-03-01 00:00:00 UTC"},
+			"X-Github-Sso":                           []string{"partial-results; organizations=1"},
+		},
+		Body: io.NopCloser(strings.NewReader("content")),
+	}
+	resp := tr.cachedResponse(req, cached)
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("cachedResponse() Content-Type = %q, want %q", got, "application/json")
+	}
+	for _, key := range []string{"X-Oauth-Scopes", "Github-Authentication-Token-Expiration", "X-Github-Sso"} {
+		if _, ok := resp.Header[key]; ok {
+			t.Errorf("cachedResponse() replayed the %s header", key)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package ghtransport
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheInfo describes how a cache handled a request. The fields up to (and including) Operation correspond
+// to the parameters of a "Cache-Status" header member (see ParseCacheStatus), the rest are only known to the
+// Transport that handled the request (see Info).
+type CacheInfo struct {
+	// Cache is the name identifying the cache, see Options.CacheName.
+	Cache string
+	// Hit is true if the response was served from the cache (including after a 304 Not Modified).
+	Hit bool
+	// Forward is the reason the request was forwarded upstream (ex: "uri-miss", "stale", "method", "bypass").
+	Forward string
+	// ForwardStatus is the status code of the forwarded request.
+	ForwardStatus int
+	// Stored is true if the response was stored.
+	Stored bool
+	// Collapsed is true if the request was collapsed with another request.
+	Collapsed bool
+	// TTL is how long the response remains fresh, negative if it is stale. It is zero if unknown.
+	TTL time.Duration
+	// Detail is the implementation-specific detail (ex: "stale-if-error", "immutable").
+	Detail string
+	// Speculation is the name of the speculative ETag guess that matched, if any (see Speculation).
+	Speculation string
+	// Operation is the GitHub REST API operation of the request (ex: "repos/get"), if known.
+	Operation string
+
+	// StoredAt is when the cached response that was used was stored, if known.
+	StoredAt time.Time
+	// StoredBy is the hashed token (see HashToken) of the principal that stored the cached response that was
+	// used, if known.
+	StoredBy string
+	// APIVersion is the GitHub REST API version the cached response that was used was stored for, if known.
+	APIVersion string
+	// RequestID is the "X-Github-Request-Id" of the upstream response, if a request was sent upstream.
+	RequestID string
+	// CachedRequestID is the "X-Github-Request-Id" of the cached response that was used, if any.
+	CachedRequestID string
+	// Variant identifies the stored variant that was used, if multiple are kept (see Options.MaxVariants).
+	Variant string
+}
+
+// String renders the CacheInfo as a "Cache-Status" header member, per RFC 9211.
+func (info CacheInfo) String() string {
+	var b strings.Builder
+	b.WriteString(info.Cache)
+	if info.Hit {
+		b.WriteString("; hit")
+	}
+	if info.Forward != "" {
+		b.WriteString("; fwd=" + info.Forward)
+	}
+	if info.ForwardStatus != 0 {
+		b.WriteString("; fwd-status=" + strconv.Itoa(info.ForwardStatus))
+	}
+	if info.Stored {
+		b.WriteString("; stored")
+	}
+	if info.Collapsed {
+		b.WriteString("; collapsed")
+	}
+	if info.TTL != 0 {
+		b.WriteString("; ttl=" + strconv.FormatInt(int64(info.TTL/time.Second), 10))
+	}
+	if info.Detail != "" {
+		b.WriteString("; detail=" + info.Detail)
+	}
+	if info.Operation != "" {
+		b.WriteString("; operation=" + info.Operation)
+	}
+	return b.String()
+}
+
+// ParseCacheStatus parses a "Cache-Status" header value (ex: produced by another Transport), returning a
+// CacheInfo for each member in order, per RFC 9211. Unknown parameters are ignored.
+func ParseCacheStatus(value string) []CacheInfo {
+	var infos []CacheInfo
+	for _, member := range splitQuoted(value, ',') {
+		params := splitQuoted(member, ';')
+		info := CacheInfo{Cache: unquote(params[0])}
+		if info.Cache == "" {
+			continue
+		}
+		for _, param := range params[1:] {
+			key, val, _ := strings.Cut(param, "=")
+			val = unquote(val)
+			switch key {
+			case "hit":
+				info.Hit = val == "" || val == "?1"
+			case "fwd":
+				info.Forward = val
+			case "fwd-status":
+				info.ForwardStatus, _ = strconv.Atoi(val)
+			case "stored":
+				info.Stored = val == "" || val == "?1"
+			case "collapsed":
+				info.Collapsed = val == "" || val == "?1"
+			case "ttl":
+				if seconds, err := strconv.ParseInt(val, 10, 64); err == nil {
+					info.TTL = time.Duration(seconds) * time.Second
+				}
+			case "detail":
+				info.Detail = val
+				if speculation, ok := strings.CutPrefix(val, "speculative-"); ok {
+					info.Speculation = speculation
+				}
+			case "operation":
+				info.Operation = val
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// splitQuoted splits the value on sep, ignoring any sep within a quoted string, trimming whitespace.
+func splitQuoted(value string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for idx := 0; idx < len(value); idx++ {
+		switch c := value[idx]; {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, strings.TrimSpace(value[start:idx]))
+			start = idx + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(value[start:]))
+}
+
+// unquote removes the quotes (and escapes) of a structured field string, per RFC 8941 3.3.3.
+func unquote(val string) string {
+	if len(val) < 2 || val[0] != '"' || val[len(val)-1] != '"' {
+		return val
+	}
+	var b strings.Builder
+	for idx := 1; idx < len(val)-1; idx++ {
+		if val[idx] == '\\' && idx+1 < len(val)-1 {
+			idx++
+		}
+		b.WriteByte(val[idx])
+	}
+	return b.String()
+}
+
+// cacheInfoKey is the context.Context key of the *CacheInfo being collected for a request.
+type cacheInfoKey struct{}
+
+// withCacheInfo returns a copy of the context collecting the CacheInfo of a request.
+func withCacheInfo(ctx context.Context) (context.Context, *CacheInfo) {
+	info := &CacheInfo{}
+	return context.WithValue(ctx, cacheInfoKey{}, info), info
+}
+
+// cacheInfoFrom returns the CacheInfo being collected for the request, or nil.
+func cacheInfoFrom(ctx context.Context) *CacheInfo {
+	info, _ := ctx.Value(cacheInfoKey{}).(*CacheInfo)
+	return info
+}
+
+// noteCached records the details of the cached response used to answer the request.
+func (t *Transport) noteCached(req *http.Request, cached *http.Response) {
+	if info := cacheInfoFrom(req.Context()); info != nil {
+		info.StoredAt, _ = http.ParseTime(cached.Header.Get(StoredAtHeader))
+		info.StoredBy = cached.Header.Get(StoredByHeader)
+		info.APIVersion = cached.Header.Get(APIVersionHeader)
+		info.CachedRequestID = cached.Header.Get("X-Github-Request-Id")
+		if t.opts.MaxVariants > 1 {
+			info.Variant = variantID(t.storedVaried(cached.Header))
+		}
+	}
+}
+
+// noteUpstream records the details of the upstream response to the request, including its rate limit state.
+func (t *Transport) noteUpstream(req *http.Request, resp *http.Response) {
+	t.noteRateLimit(req, resp)
+	if info := cacheInfoFrom(req.Context()); info != nil {
+		info.RequestID = resp.Header.Get("X-Github-Request-Id")
+	}
+}
+
+// Info returns the CacheInfo describing how the Transport handled the request of the response. If the
+// response was not returned by a Transport, ok is false.
+func Info(resp *http.Response) (_ CacheInfo, ok bool) {
+	if resp == nil || resp.Request == nil {
+		return CacheInfo{}, false
+	}
+	info := cacheInfoFrom(resp.Request.Context())
+	if info == nil {
+		return CacheInfo{}, false
+	}
+	return *info, true
+}
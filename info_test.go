@@ -0,0 +1,122 @@
+package ghtransport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCacheStatus(t *testing.T) {
+	tests := map[string]struct {
+		Value    string
+		Expected []CacheInfo
+	}{
+		"empty": {
+			Value:    "",
+			Expected: nil,
+		},
+		"hit": {
+			Value:    "github-conditional-http-transport; hit; ttl=-30; collapsed; operation=repos/get",
+			Expected: []CacheInfo{{Cache: "github-conditional-http-transport", Hit: true, TTL: -30 * time.Second, Collapsed: true, Operation: "repos/get"}},
+		},
+		"forward": {
+			Value:    "github-conditional-http-transport; fwd=uri-miss; fwd-status=200; stored",
+			Expected: []CacheInfo{{Cache: "github-conditional-http-transport", Forward: "uri-miss", ForwardStatus: 200, Stored: true}},
+		},
+		"speculative": {
+			Value:    "github-conditional-http-transport; hit; detail=speculative-empty-array",
+			Expected: []CacheInfo{{Cache: "github-conditional-http-transport", Hit: true, Detail: "speculative-empty-array", Speculation: "empty-array"}},
+		},
+		"multiple": {
+			Value: `"edge, cache"; fwd=stale; detail="a; b", github-conditional-http-transport; hit; unknown=1`,
+			Expected: []CacheInfo{
+				{Cache: "edge, cache", Forward: "stale", Detail: "a; b"},
+				{Cache: "github-conditional-http-transport", Hit: true},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := ParseCacheStatus(test.Value); !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("ParseCacheStatus(%q) = %+v, want %+v", test.Value, got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestCacheInfo_String(t *testing.T) {
+	tests := []string{
+		cacheStatusHit(CacheName),
+		cacheStatusHitSpeculative(CacheName, "empty-array"),
+		cacheStatusForward(CacheName, "uri-miss", http.StatusOK, true),
+		cacheStatusOperation(cacheStatusHitTTL(CacheName, time.Minute), "repos/get"),
+	}
+	for _, value := range tests {
+		infos := ParseCacheStatus(value)
+		if len(infos) != 1 {
+			t.Fatalf("ParseCacheStatus(%q) returned %d members, want 1", value, len(infos))
+		}
+		if got := infos[0].String(); got != value {
+			t.Errorf("(CacheInfo).String() = %q, want %q", got, value)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	storedAt := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	tr := NewTransport(&mockStorage{
+		getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Status:     "200 OK",
+				Header: http.Header{
+					"Etag":                []string{`"tag1"`},
+					"X-Github-Request-Id": []string{"CACHED"},
+					StoredAtHeader:        []string{storedAt.Format(http.TimeFormat)},
+				},
+				Body: io.NopCloser(strings.NewReader("cached content")),
+			}, nil
+		},
+	}, &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusNotModified,
+				Header:     http.Header{"X-Github-Request-Id": []string{"LIVE"}},
+				Body:       io.NopCloser(strings.NewReader("")),
+			}, nil
+		},
+	})
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	resp.Body.Close()
+
+	info, ok := Info(resp)
+	if !ok {
+		t.Fatal("Info() ok = false, want true")
+	}
+	want := CacheInfo{
+		Cache:           CacheName,
+		Hit:             true,
+		Operation:       "repos/get",
+		StoredAt:        storedAt,
+		RequestID:       "LIVE",
+		CachedRequestID: "CACHED",
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("Info() = %+v, want %+v", info, want)
+	}
+
+	if _, ok := Info(&http.Response{Request: req}); ok {
+		t.Errorf("Info() of a response not returned by a Transport ok = true, want false")
+	}
+}
@@ -0,0 +1,54 @@
+package ghtransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// lease acquires the revalidation lease for req, returning the func to release it. If the lease is held by
+// another process, it waits (up to LeaseWait) for it to be released instead, returning when the wait started
+// (zero if it gave up waiting), such that the caller can tell if the stored response was revalidated
+// meanwhile (see validatedSince). If the Leaser fails (and the StorageErrorPolicy allows it), the revalidation
+// proceeds without a lease.
+func (t *Transport) lease(req *http.Request, leaser Leaser) (waited time.Time, _ func(), _ error) {
+	ctx := req.Context()
+	release, err := leaser.Lease(ctx, req)
+	if err != nil {
+		return time.Time{}, nil, t.storageError(req, "Lease", err)
+	}
+	if release != nil {
+		return time.Time{}, func() {
+			if err := release(context.WithoutCancel(ctx)); err != nil {
+				_ = t.storageError(req, "Release", err) // The lease will expire on its own
+			}
+		}, nil
+	}
+
+	// Someone else is revalidating, wait for them to finish (or give up waiting)
+	start := t.now()
+	waitCtx, cancel := context.WithTimeout(ctx, t.opts.LeaseWait)
+	err = leaser.Wait(waitCtx, req)
+	cancel()
+	if err != nil {
+		if ctx.Err() != nil {
+			return time.Time{}, nil, ctx.Err()
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			if err := t.storageError(req, "Wait", err); err != nil {
+				return time.Time{}, nil, err
+			}
+		}
+		return time.Time{}, nil, nil
+	}
+	return start, nil, nil
+}
+
+// validatedSince reports if the cached response was stored or validated (see validatedAt) no earlier than
+// the given time, with the (one second) precision of the recorded times. A failed revalidation leaves the
+// stored response as it was.
+func validatedSince(cached *http.Response, since time.Time) bool {
+	at, ok := validatedAt(cached)
+	return ok && !at.Before(since.Truncate(time.Second))
+}
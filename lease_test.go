@@ -0,0 +1,175 @@
+package ghtransport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockLeaser extends mockStorage with the Leaser interface.
+type mockLeaser struct {
+	mockStorage
+	leaseFunc func(context.Context, *http.Request) (func(context.Context) error, error)
+	waitFunc  func(context.Context, *http.Request) error
+}
+
+func (m *mockLeaser) Lease(ctx context.Context, req *http.Request) (func(context.Context) error, error) {
+	return m.leaseFunc(ctx, req)
+}
+
+func (m *mockLeaser) Wait(ctx context.Context, req *http.Request) error {
+	return m.waitFunc(ctx, req)
+}
+
+func TestTransport_RoundTrip_Lease(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	cachedResponse := func(etag, body string, storedAt time.Time) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Header: http.Header{
+				"Etag":         []string{etag},
+				StoredAtHeader: []string{storedAt.Format(http.TimeFormat)},
+			},
+			Body: io.NopCloser(strings.NewReader(body)),
+		}
+	}
+	// A stored response after it was validated (at now) by the process holding the lease
+	validatedResponse := func(etag, body string) *http.Response {
+		resp := cachedResponse(etag, body, now.Add(-time.Hour))
+		resp.Header.Set(ValidatedAtHeader, now.Format(http.TimeFormat))
+		return resp
+	}
+	// A response stored for another principal than the (unauthenticated) request
+	variedResponse := func(etag, body string) *http.Response {
+		resp := validatedResponse(etag, body)
+		resp.Header.Set("Vary", "Authorization")
+		resp.Header.Set(VaryPrefix+"Authorization", HashToken("Bearer other"))
+		return resp
+	}
+	tests := map[string]struct {
+		Held            bool
+		WaitErr         error
+		Stored          []*http.Response // returned by successive (Storage).Get calls
+		WantUpstream    bool
+		WantReleased    bool
+		WantBody        string
+		WantCacheStatus string
+	}{
+		"acquired": {
+			Stored:          []*http.Response{nil},
+			WantUpstream:    true,
+			WantReleased:    true,
+			WantBody:        "upstream",
+			WantCacheStatus: cacheStatusForward(CacheName, "uri-miss", http.StatusOK, true),
+		},
+		"held_then_stored": {
+			Held:            true,
+			Stored:          []*http.Response{nil, cachedResponse(`"tag1"`, "stored by other", now)},
+			WantBody:        "stored by other",
+			WantCacheStatus: cacheStatusHit(CacheName) + "; collapsed",
+		},
+		"held_not_modified": {
+			Held:            true,
+			Stored:          []*http.Response{cachedResponse(`"tag1"`, "old", now.Add(-time.Hour)), validatedResponse(`"tag1"`, "old")},
+			WantBody:        "old",
+			WantCacheStatus: cacheStatusHit(CacheName) + "; collapsed",
+		},
+		"held_failed": {
+			// The revalidation of the process holding the lease failed, so the stored response is unchanged
+			Held:            true,
+			Stored:          []*http.Response{cachedResponse(`"tag1"`, "old", now.Add(-time.Hour)), cachedResponse(`"tag1"`, "old", now.Add(-time.Hour))},
+			WantUpstream:    true,
+			WantBody:        "upstream",
+			WantCacheStatus: cacheStatusForward(CacheName, "stale", http.StatusOK, true),
+		},
+		"held_not_modified_other_principal": {
+			Held:            true,
+			Stored:          []*http.Response{cachedResponse(`"tag1"`, "old", now.Add(-time.Hour)), variedResponse(`"tag1"`, "old")},
+			WantUpstream:    true,
+			WantBody:        "upstream",
+			WantCacheStatus: cacheStatusForward(CacheName, "stale", http.StatusOK, true),
+		},
+		"held_timeout": {
+			Held:            true,
+			WaitErr:         context.DeadlineExceeded,
+			Stored:          []*http.Response{nil},
+			WantUpstream:    true,
+			WantBody:        "upstream",
+			WantCacheStatus: cacheStatusForward(CacheName, "uri-miss", http.StatusOK, true),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var released, upstream bool
+			gets := test.Stored
+			storage := &mockLeaser{
+				mockStorage: mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						if len(gets) == 0 {
+							return nil, errors.New("unexpected (Storage).Get")
+						}
+						resp := gets[0]
+						gets = gets[1:]
+						return resp, nil
+					},
+				},
+				leaseFunc: func(ctx context.Context, req *http.Request) (func(context.Context) error, error) {
+					if test.Held {
+						return nil, nil
+					}
+					return func(context.Context) error {
+						released = true
+						return nil
+					}, nil
+				},
+				waitFunc: func(ctx context.Context, req *http.Request) error {
+					return test.WaitErr
+				},
+			}
+			tr := New(Options{
+				Storage: storage,
+				Parent: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						upstream = true
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Header:     http.Header{"Etag": []string{`"tag2"`}},
+							Body:       io.NopCloser(strings.NewReader("upstream")),
+						}, nil
+					},
+				},
+				LeaseWait: time.Second,
+			})
+			tr.now = func() time.Time { return now }
+
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			defer resp.Body.Close()
+
+			if body, _ := io.ReadAll(resp.Body); string(body) != test.WantBody {
+				t.Errorf("RoundTrip() body = %q, want %q", body, test.WantBody)
+			}
+			if got, want := resp.Header.Get("Cache-Status"), withOperation(test.WantCacheStatus); got != want {
+				t.Errorf("RoundTrip() %s = %q, want %q", "Cache-Status", got, want)
+			}
+			if upstream != test.WantUpstream {
+				t.Errorf("upstream RoundTrip called = %v, want %v", upstream, test.WantUpstream)
+			}
+			if released != test.WantReleased {
+				t.Errorf("lease released = %v, want %v", released, test.WantReleased)
+			}
+		})
+	}
+}
@@ -40,6 +40,11 @@ func (s *Storage) Put(ctx context.Context, resp *http.Response) error {
 	return nil
 }
 
+func (s *Storage) Delete(ctx context.Context, req *http.Request) error {
+	s.Map.Delete(req.URL.String())
+	return nil
+}
+
 // NewStorage returns a new, empty Storage.
 func NewStorage() *Storage {
 	return &Storage{}
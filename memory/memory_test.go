@@ -94,4 +94,19 @@ func TestStorage(t *testing.T) {
 		t.Fatalf("(*Storage).Get corrupted (*http.Response).Body.Close: %v", err)
 	}
 
+	// Ensure we can delete the response from the cache
+	if err := storage.Delete(t.Context(), &http.Request{
+		Method: http.MethodGet,
+		URL:    testURL,
+	}); err != nil {
+		t.Fatalf("(*Storage).Delete failed: %v", err)
+	}
+	if deletedResp, err := storage.Get(t.Context(), &http.Request{
+		Method: http.MethodGet,
+		URL:    testURL,
+	}); err != nil {
+		t.Fatalf("(*Storage).Get failed: %v", err)
+	} else if deletedResp != nil {
+		t.Fatalf("(*Storage).Get returned non-nil response for deleted URL: %v", deletedResp)
+	}
 }
@@ -0,0 +1,61 @@
+package ghtransport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WithOnlyIfCached returns a copy of the context that makes the Transport answer the request only from its
+// Storage, per the RFC 9111 "only-if-cached" request directive. See Options.OnlyIfCached.
+func WithOnlyIfCached(ctx context.Context) context.Context {
+	return WithDirectives(ctx, RequestDirectives{OnlyIfCached: true})
+}
+
+// onlyIfCached reports if the request may only be answered from the Storage.
+func (t *Transport) onlyIfCached(req *http.Request) bool {
+	return t.opts.OnlyIfCached || directivesFrom(req.Context()).OnlyIfCached
+}
+
+// gatewayTimeout builds the synthetic "504 Gateway Timeout" response for a request that could not be answered
+// from the Storage, per RFC 9111 5.2.1.7.
+func (t *Transport) gatewayTimeout(req *http.Request) *http.Response {
+	resp := &http.Response{
+		Status:     "504 Gateway Timeout",
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+	setCacheStatus(resp, cacheStatusDetail(t.opts.CacheName, "only-if-cached"), "MISS")
+	return resp
+}
+
+// offline answers the request only from the Storage, without ever calling the parent http.RoundTripper.
+// Only a stored response with identical Vary headers (ex: the same token) is used, anything else is a
+// "504 Gateway Timeout".
+func (t *Transport) offline(req *http.Request) (*http.Response, error) {
+	if ok, _ := t.cacheable(req); !ok || t.opts.Storage == nil {
+		return t.gatewayTimeout(req), nil
+	}
+	cached, err := t.opts.Storage.Get(req.Context(), req)
+	if err != nil {
+		if err := t.storageError(req, "Get", err); err != nil {
+			return nil, err
+		}
+		return t.gatewayTimeout(req), nil
+	}
+	if cached, err = t.selectVariant(req, cached); err != nil {
+		discardResponse(cached)
+		return nil, err
+	}
+	if cached == nil || !t.identicalVary(req, cached) {
+		discardResponse(cached)
+		return t.gatewayTimeout(req), nil
+	}
+	return t.freshResponse(req, cached, cacheStatusHit(t.opts.CacheName)), nil
+}
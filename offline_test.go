@@ -0,0 +1,117 @@
+package ghtransport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTransport_RoundTrip_OnlyIfCached(t *testing.T) {
+	cached := func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Header: http.Header{
+				"Etag":                       []string{`"tag1"`},
+				"Vary":                       []string{"Authorization"},
+				VaryPrefix + "Authorization": []string{HashToken("Bearer hunter2")},
+			},
+			Body:          io.NopCloser(strings.NewReader("cached content")),
+			ContentLength: 14,
+		}, nil
+	}
+	tests := map[string]struct {
+		Method          string
+		Authorization   string
+		Get             func(context.Context, *http.Request) (*http.Response, error)
+		NilStorage      bool
+		WantStatusCode  int
+		WantBody        string
+		WantCacheStatus string
+	}{
+		"hit": {
+			Method:          http.MethodGet,
+			Authorization:   "Bearer hunter2",
+			Get:             cached,
+			WantStatusCode:  http.StatusOK,
+			WantBody:        "cached content",
+			WantCacheStatus: cacheStatusHit(CacheName),
+		},
+		"miss": {
+			Method:          http.MethodGet,
+			Authorization:   "Bearer hunter2",
+			Get:             func(context.Context, *http.Request) (*http.Response, error) { return nil, nil },
+			WantStatusCode:  http.StatusGatewayTimeout,
+			WantCacheStatus: cacheStatusDetail(CacheName, "only-if-cached"),
+		},
+		"other_token": {
+			Method:          http.MethodGet,
+			Authorization:   "Bearer hunter3",
+			Get:             cached,
+			WantStatusCode:  http.StatusGatewayTimeout,
+			WantCacheStatus: cacheStatusDetail(CacheName, "only-if-cached"),
+		},
+		"nil_storage": {
+			Method:          http.MethodGet,
+			NilStorage:      true,
+			WantStatusCode:  http.StatusGatewayTimeout,
+			WantCacheStatus: cacheStatusDetail(CacheName, "only-if-cached"),
+		},
+		"not_cacheable": {
+			Method:          http.MethodPost,
+			Get:             cached,
+			WantStatusCode:  http.StatusGatewayTimeout,
+			WantCacheStatus: cacheStatusDetail(CacheName, "only-if-cached"),
+		},
+	}
+	for name, test := range tests {
+		for _, mode := range []string{"transport", "context"} {
+			t.Run(name+"/"+mode, func(t *testing.T) {
+				opts := Options{
+					Parent: &mockRoundTripper{
+						roundTripFunc: func(req *http.Request) (*http.Response, error) {
+							t.Errorf("unexpected upstream request")
+							return nil, errors.New("offline")
+						},
+					},
+					OnlyIfCached: mode == "transport",
+				}
+				if !test.NilStorage {
+					opts.Storage = &mockStorage{getFunc: test.Get}
+				}
+				tr := New(opts)
+
+				ctx := context.Background()
+				if mode == "context" {
+					ctx = WithOnlyIfCached(ctx)
+				}
+				req, err := http.NewRequestWithContext(ctx, test.Method, "https://api.github.com/repos/foo/bar", nil)
+				if err != nil {
+					t.Fatalf("failed to create request: %v", err)
+				}
+				req.Header.Set("Authorization", test.Authorization)
+				resp, err := tr.RoundTrip(req)
+				if err != nil {
+					t.Fatalf("RoundTrip() error = %v", err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != test.WantStatusCode {
+					t.Errorf("RoundTrip() status = %d, want %d", resp.StatusCode, test.WantStatusCode)
+				}
+				if body, _ := io.ReadAll(resp.Body); string(body) != test.WantBody {
+					t.Errorf("RoundTrip() body = %q, want %q", body, test.WantBody)
+				}
+				want := test.WantCacheStatus
+				if test.Method == http.MethodGet {
+					want = withOperation(want)
+				}
+				if got := resp.Header.Get("Cache-Status"); got != want {
+					t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Command gen compiles GitHub's published OpenAPI description into the operation table of the openapi package.
+//
+// It is run offline via "go generate", the generated file is checked in.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// description is the subset of an OpenAPI description needed to build the operation table.
+type description struct {
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// operation is the subset of an OpenAPI operation needed to build the operation table.
+type operation struct {
+	OperationID string `json:"operationId"`
+}
+
+// methods are the HTTP methods that may be used as keys of an OpenAPI path item.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// read reads the OpenAPI description from a URL or a local file.
+func read(input string) ([]byte, error) {
+	if !strings.HasPrefix(input, "https://") {
+		return os.ReadFile(input)
+	}
+	resp, err := http.Get(input)
+	if err != nil {
+		return nil, fmt.Errorf("http.Get failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http.Get returned %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// generate renders the operation table for the OpenAPI description.
+func generate(data []byte) ([]byte, error) {
+	var desc description
+	if err := json.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal failed: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"go run ./internal/gen\"; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package openapi\n\n")
+	fmt.Fprintf(&buf, "// operations are the operations of the GitHub REST API, sorted by path then method.\n")
+	fmt.Fprintf(&buf, "var operations = []Operation{\n")
+	for _, path := range slices.Sorted(maps.Keys(desc.Paths)) {
+		for _, method := range methods {
+			raw, ok := desc.Paths[path][method]
+			if !ok {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("json.Unmarshal of %s %s failed: %w", method, path, err)
+			}
+			if op.OperationID == "" {
+				continue
+			}
+			fmt.Fprintf(&buf, "\t{ID: %q, Method: %q, Path: %q},\n", op.OperationID, strings.ToUpper(method), path)
+		}
+	}
+	fmt.Fprintf(&buf, "}\n")
+	return format.Source(buf.Bytes())
+}
+
+func main() {
+	input := flag.String("input", "https://raw.githubusercontent.com/github/rest-api-description/main/descriptions/api.github.com/api.github.com.json", "URL or file of the OpenAPI description")
+	output := flag.String("output", "operations.go", "file to write the operation table to")
+	flag.Parse()
+
+	data, err := read(*input)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", *input, err)
+	}
+	src, err := generate(data)
+	if err != nil {
+		log.Fatalf("failed to generate: %v", err)
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatalf("os.WriteFile failed: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_generate(t *testing.T) {
+	src, err := generate([]byte(`{"paths": {
+		"/repos/{owner}/{repo}": {
+			"parameters": [],
+			"patch": {"operationId": "repos/update"},
+			"get": {"operationId": "repos/get"}
+		},
+		"/": {"get": {"operationId": "meta/root"}}
+	}}`))
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	want := `var operations = []Operation{
+	{ID: "meta/root", Method: "GET", Path: "/"},
+	{ID: "repos/get", Method: "GET", Path: "/repos/{owner}/{repo}"},
+	{ID: "repos/update", Method: "PATCH", Path: "/repos/{owner}/{repo}"},
+}`
+	if !strings.Contains(string(src), want) {
+		t.Errorf("generate() =\n%s\nwant it to contain\n%s", src, want)
+	}
+}
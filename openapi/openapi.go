@@ -0,0 +1,124 @@
+// Package openapi classifies GitHub REST API requests by the operation they belong to (ex: "repos/get"),
+// using a path matcher over an operation table. The table is meant to be compiled from GitHub's published
+// OpenAPI description by "go generate" (see internal/gen), but the checked-in table is still a
+// hand-maintained subset of the commonly used operations, so other requests are not classified.
+package openapi
+
+//go:generate go run ./internal/gen -output operations.go
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Operation is a GitHub REST API operation.
+type Operation struct {
+	// ID is the "operationId" of the operation (ex: "issues/list-for-repo").
+	ID string
+	// Method is the HTTP method of the operation (ex: "GET").
+	Method string
+	// Path is the path template of the operation (ex: "/repos/{owner}/{repo}/issues").
+	Path string
+}
+
+// greedy are the path parameters that may contain slashes (ex: a file path or a branch name), so as the last
+// segment of an operation's path they match the remainder of the path if nothing more specific does.
+var greedy = map[string]bool{
+	"{path}":     true,
+	"{ref}":      true,
+	"{branch}":   true,
+	"{basehead}": true,
+}
+
+// node is a path segment in the operation trie. As every parameter segment below a node shares the same
+// child, only the operations ending with a greedy parameter are kept in greedy.
+type node struct {
+	literals map[string]*node
+	param    *node
+	methods  map[string]Operation
+	greedy   map[string]Operation
+}
+
+// insert adds the operation to the trie.
+func (n *node) insert(op Operation) {
+	var last string
+	for segment := range strings.SplitSeq(strings.Trim(op.Path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		last = segment
+		if strings.HasPrefix(segment, "{") {
+			if n.param == nil {
+				n.param = &node{}
+			}
+			n = n.param
+			continue
+		}
+		if n.literals == nil {
+			n.literals = make(map[string]*node)
+		}
+		child, ok := n.literals[segment]
+		if !ok {
+			child = &node{}
+			n.literals[segment] = child
+		}
+		n = child
+	}
+	if n.methods == nil {
+		n.methods = make(map[string]Operation)
+	}
+	n.methods[op.Method] = op
+	if greedy[last] {
+		if n.greedy == nil {
+			n.greedy = make(map[string]Operation)
+		}
+		n.greedy[op.Method] = op
+	}
+}
+
+// lookup finds the operations (by method) matching the path segments, preferring literal segments over
+// parameters.
+func (n *node) lookup(segments []string) map[string]Operation {
+	if len(segments) == 0 {
+		return n.methods
+	}
+	if child, ok := n.literals[segments[0]]; ok {
+		if found := child.lookup(segments[1:]); found != nil {
+			return found
+		}
+	}
+	if n.param != nil && segments[0] != "" {
+		if found := n.param.lookup(segments[1:]); found != nil {
+			return found
+		}
+		if n.param.greedy != nil {
+			return n.param.greedy
+		}
+	}
+	return nil
+}
+
+// root is the operation trie, built on first use.
+var root = sync.OnceValue(func() *node {
+	root := &node{}
+	for _, op := range operations {
+		root.insert(op)
+	}
+	return root
+})
+
+// Lookup returns the operation for the HTTP method and URL path. The "/api/v3" prefix of GitHub Enterprise
+// Server is ignored and a HEAD request is classified as the corresponding GET operation.
+func Lookup(method, path string) (Operation, bool) {
+	path = strings.TrimPrefix(path, "/api/v3")
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	var segments []string
+	if path = strings.Trim(path, "/"); path != "" {
+		segments = strings.Split(path, "/")
+	}
+	op, ok := root().lookup(segments)[method]
+	return op, ok
+}
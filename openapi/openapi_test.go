@@ -0,0 +1,68 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := map[string]struct {
+		Method   string
+		Path     string
+		Expected string
+	}{
+		"root":            {Method: "GET", Path: "/", Expected: "meta/root"},
+		"repo":            {Method: "GET", Path: "/repos/foo/bar", Expected: "repos/get"},
+		"repo_trailing":   {Method: "GET", Path: "/repos/foo/bar/", Expected: "repos/get"},
+		"repo_head":       {Method: "HEAD", Path: "/repos/foo/bar", Expected: "repos/get"},
+		"repo_patch":      {Method: "PATCH", Path: "/repos/foo/bar", Expected: "repos/update"},
+		"enterprise":      {Method: "GET", Path: "/api/v3/repos/foo/bar/issues", Expected: "issues/list-for-repo"},
+		"literal":         {Method: "GET", Path: "/repos/foo/bar/releases/latest", Expected: "repos/get-latest-release"},
+		"param":           {Method: "GET", Path: "/repos/foo/bar/releases/1234", Expected: "repos/get-release"},
+		"nested":          {Method: "GET", Path: "/repos/foo/bar/commits/deadbeef/pulls", Expected: "repos/list-pull-requests-associated-with-commit"},
+		"greedy_path":     {Method: "GET", Path: "/repos/foo/bar/contents/a/b/c.go", Expected: "repos/get-content"},
+		"greedy_branch":   {Method: "GET", Path: "/repos/foo/bar/branches/feature/x", Expected: "repos/get-branch"},
+		"greedy_specific": {Method: "GET", Path: "/repos/foo/bar/branches/main/protection", Expected: "repos/get-branch-protection"},
+		"greedy_ref":      {Method: "GET", Path: "/repos/foo/bar/git/ref/heads/main", Expected: "git/get-ref"},
+		"unknown_method":  {Method: "POST", Path: "/repos/foo/bar/releases/latest", Expected: ""},
+		"unknown_path":    {Method: "GET", Path: "/repos/foo/bar/issues/1/unknown", Expected: ""},
+		"empty_segment":   {Method: "GET", Path: "/repos/foo//issues", Expected: ""},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			op, ok := Lookup(test.Method, test.Path)
+			if ok != (test.Expected != "") {
+				t.Fatalf("Lookup(%s, %s) ok = %v, want %v", test.Method, test.Path, ok, test.Expected != "")
+			}
+			if op.ID != test.Expected {
+				t.Errorf("Lookup(%s, %s) = %q, want %q", test.Method, test.Path, op.ID, test.Expected)
+			}
+		})
+	}
+}
+
+func TestNode_lookup(t *testing.T) {
+	root := &node{}
+	for _, op := range []Operation{
+		{ID: "get-thing", Method: "GET", Path: "/things/{thing_id}"},
+		{ID: "list-thing-statuses", Method: "GET", Path: "/things/{ref}/statuses"},
+		{ID: "get-file", Method: "GET", Path: "/files/{path}"},
+		{ID: "get-file-meta", Method: "GET", Path: "/files/{path}/meta"},
+	} {
+		root.insert(op)
+	}
+	tests := map[string]string{
+		"/things/1":             "get-thing",
+		"/things/main/statuses": "list-thing-statuses",
+		"/things/a/b":           "", // {ref} is only greedy as the last segment, and {thing_id} never is
+		"/files/a/b/c.go":       "get-file",
+		"/files/a/meta":         "get-file-meta",
+	}
+	for path, want := range tests {
+		op, ok := root.lookup(strings.Split(strings.Trim(path, "/"), "/"))[http.MethodGet]
+		if ok != (want != "") || op.ID != want {
+			t.Errorf("lookup(%s) = %q, %v, want %q", path, op.ID, ok, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// This file is NOT generated yet: it is a hand-maintained subset of the GitHub REST API operations, in the
+// format of "go run ./internal/gen". Run "go generate" with access to GitHub's published OpenAPI description
+// to replace it with the complete operation table.
+
+package openapi
+
+// operations are the (commonly used) operations of the GitHub REST API, sorted by path then method.
+var operations = []Operation{
+	{ID: "meta/root", Method: "GET", Path: "/"},
+	{ID: "apps/get-authenticated", Method: "GET", Path: "/app"},
+	{ID: "apps/list-installations", Method: "GET", Path: "/app/installations"},
+	{ID: "emojis/get", Method: "GET", Path: "/emojis"},
+	{ID: "gists/get", Method: "GET", Path: "/gists/{gist_id}"},
+	{ID: "apps/list-repos-accessible-to-installation", Method: "GET", Path: "/installation/repositories"},
+	{ID: "issues/list", Method: "GET", Path: "/issues"},
+	{ID: "licenses/get", Method: "GET", Path: "/licenses/{license}"},
+	{ID: "meta/get", Method: "GET", Path: "/meta"},
+	{ID: "activity/list-notifications-for-authenticated-user", Method: "GET", Path: "/notifications"},
+	{ID: "meta/get-octocat", Method: "GET", Path: "/octocat"},
+	{ID: "orgs/get", Method: "GET", Path: "/orgs/{org}"},
+	{ID: "orgs/update", Method: "PATCH", Path: "/orgs/{org}"},
+	{ID: "orgs/list-app-installations", Method: "GET", Path: "/orgs/{org}/installations"},
+	{ID: "orgs/list-members", Method: "GET", Path: "/orgs/{org}/members"},
+	{ID: "orgs/check-membership-for-user", Method: "GET", Path: "/orgs/{org}/members/{username}"},
+	{ID: "repos/list-for-org", Method: "GET", Path: "/orgs/{org}/repos"},
+	{ID: "repos/create-in-org", Method: "POST", Path: "/orgs/{org}/repos"},
+	{ID: "teams/list", Method: "GET", Path: "/orgs/{org}/teams"},
+	{ID: "teams/get-by-name", Method: "GET", Path: "/orgs/{org}/teams/{team_slug}"},
+	{ID: "teams/list-members-in-org", Method: "GET", Path: "/orgs/{org}/teams/{team_slug}/members"},
+	{ID: "teams/list-repos-in-org", Method: "GET", Path: "/orgs/{org}/teams/{team_slug}/repos"},
+	{ID: "rate-limit/get", Method: "GET", Path: "/rate_limit"},
+	{ID: "repos/get", Method: "GET", Path: "/repos/{owner}/{repo}"},
+	{ID: "repos/delete", Method: "DELETE", Path: "/repos/{owner}/{repo}"},
+	{ID: "repos/update", Method: "PATCH", Path: "/repos/{owner}/{repo}"},
+	{ID: "actions/list-artifacts-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/actions/artifacts"},
+	{ID: "actions/get-job-for-workflow-run", Method: "GET", Path: "/repos/{owner}/{repo}/actions/jobs/{job_id}"},
+	{ID: "actions/list-workflow-runs-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/actions/runs"},
+	{ID: "actions/get-workflow-run", Method: "GET", Path: "/repos/{owner}/{repo}/actions/runs/{run_id}"},
+	{ID: "actions/list-jobs-for-workflow-run", Method: "GET", Path: "/repos/{owner}/{repo}/actions/runs/{run_id}/jobs"},
+	{ID: "actions/list-repo-secrets", Method: "GET", Path: "/repos/{owner}/{repo}/actions/secrets"},
+	{ID: "actions/list-repo-workflows", Method: "GET", Path: "/repos/{owner}/{repo}/actions/workflows"},
+	{ID: "actions/get-workflow", Method: "GET", Path: "/repos/{owner}/{repo}/actions/workflows/{workflow_id}"},
+	{ID: "actions/list-workflow-runs", Method: "GET", Path: "/repos/{owner}/{repo}/actions/workflows/{workflow_id}/runs"},
+	{ID: "issues/list-assignees", Method: "GET", Path: "/repos/{owner}/{repo}/assignees"},
+	{ID: "repos/list-branches", Method: "GET", Path: "/repos/{owner}/{repo}/branches"},
+	{ID: "repos/get-branch", Method: "GET", Path: "/repos/{owner}/{repo}/branches/{branch}"},
+	{ID: "repos/get-branch-protection", Method: "GET", Path: "/repos/{owner}/{repo}/branches/{branch}/protection"},
+	{ID: "checks/get", Method: "GET", Path: "/repos/{owner}/{repo}/check-runs/{check_run_id}"},
+	{ID: "checks/get-suite", Method: "GET", Path: "/repos/{owner}/{repo}/check-suites/{check_suite_id}"},
+	{ID: "code-scanning/list-alerts-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/code-scanning/alerts"},
+	{ID: "repos/list-collaborators", Method: "GET", Path: "/repos/{owner}/{repo}/collaborators"},
+	{ID: "repos/get-collaborator-permission-level", Method: "GET", Path: "/repos/{owner}/{repo}/collaborators/{username}/permission"},
+	{ID: "repos/list-commits", Method: "GET", Path: "/repos/{owner}/{repo}/commits"},
+	{ID: "repos/list-pull-requests-associated-with-commit", Method: "GET", Path: "/repos/{owner}/{repo}/commits/{commit_sha}/pulls"},
+	{ID: "repos/get-commit", Method: "GET", Path: "/repos/{owner}/{repo}/commits/{ref}"},
+	{ID: "checks/list-for-ref", Method: "GET", Path: "/repos/{owner}/{repo}/commits/{ref}/check-runs"},
+	{ID: "checks/list-suites-for-ref", Method: "GET", Path: "/repos/{owner}/{repo}/commits/{ref}/check-suites"},
+	{ID: "repos/get-combined-status-for-ref", Method: "GET", Path: "/repos/{owner}/{repo}/commits/{ref}/status"},
+	{ID: "repos/list-commit-statuses-for-ref", Method: "GET", Path: "/repos/{owner}/{repo}/commits/{ref}/statuses"},
+	{ID: "repos/get-community-profile-metrics", Method: "GET", Path: "/repos/{owner}/{repo}/community/profile"},
+	{ID: "repos/compare-commits", Method: "GET", Path: "/repos/{owner}/{repo}/compare/{basehead}"},
+	{ID: "repos/get-content", Method: "GET", Path: "/repos/{owner}/{repo}/contents/{path}"},
+	{ID: "repos/create-or-update-file-contents", Method: "PUT", Path: "/repos/{owner}/{repo}/contents/{path}"},
+	{ID: "repos/delete-file", Method: "DELETE", Path: "/repos/{owner}/{repo}/contents/{path}"},
+	{ID: "repos/list-contributors", Method: "GET", Path: "/repos/{owner}/{repo}/contributors"},
+	{ID: "dependabot/list-alerts-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/dependabot/alerts"},
+	{ID: "repos/list-deployments", Method: "GET", Path: "/repos/{owner}/{repo}/deployments"},
+	{ID: "repos/create-deployment", Method: "POST", Path: "/repos/{owner}/{repo}/deployments"},
+	{ID: "repos/list-deployment-statuses", Method: "GET", Path: "/repos/{owner}/{repo}/deployments/{deployment_id}/statuses"},
+	{ID: "repos/get-all-environments", Method: "GET", Path: "/repos/{owner}/{repo}/environments"},
+	{ID: "activity/list-repo-events", Method: "GET", Path: "/repos/{owner}/{repo}/events"},
+	{ID: "repos/list-forks", Method: "GET", Path: "/repos/{owner}/{repo}/forks"},
+	{ID: "git/get-blob", Method: "GET", Path: "/repos/{owner}/{repo}/git/blobs/{file_sha}"},
+	{ID: "git/get-commit", Method: "GET", Path: "/repos/{owner}/{repo}/git/commits/{commit_sha}"},
+	{ID: "git/list-matching-refs", Method: "GET", Path: "/repos/{owner}/{repo}/git/matching-refs/{ref}"},
+	{ID: "git/get-ref", Method: "GET", Path: "/repos/{owner}/{repo}/git/ref/{ref}"},
+	{ID: "git/get-tag", Method: "GET", Path: "/repos/{owner}/{repo}/git/tags/{tag_sha}"},
+	{ID: "git/get-tree", Method: "GET", Path: "/repos/{owner}/{repo}/git/trees/{tree_sha}"},
+	{ID: "repos/list-webhooks", Method: "GET", Path: "/repos/{owner}/{repo}/hooks"},
+	{ID: "apps/get-repo-installation", Method: "GET", Path: "/repos/{owner}/{repo}/installation"},
+	{ID: "issues/list-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/issues"},
+	{ID: "issues/create", Method: "POST", Path: "/repos/{owner}/{repo}/issues"},
+	{ID: "issues/list-comments-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/issues/comments"},
+	{ID: "issues/get-comment", Method: "GET", Path: "/repos/{owner}/{repo}/issues/comments/{comment_id}"},
+	{ID: "issues/get", Method: "GET", Path: "/repos/{owner}/{repo}/issues/{issue_number}"},
+	{ID: "issues/update", Method: "PATCH", Path: "/repos/{owner}/{repo}/issues/{issue_number}"},
+	{ID: "issues/list-comments", Method: "GET", Path: "/repos/{owner}/{repo}/issues/{issue_number}/comments"},
+	{ID: "issues/create-comment", Method: "POST", Path: "/repos/{owner}/{repo}/issues/{issue_number}/comments"},
+	{ID: "issues/list-events", Method: "GET", Path: "/repos/{owner}/{repo}/issues/{issue_number}/events"},
+	{ID: "issues/list-labels-on-issue", Method: "GET", Path: "/repos/{owner}/{repo}/issues/{issue_number}/labels"},
+	{ID: "issues/list-events-for-timeline", Method: "GET", Path: "/repos/{owner}/{repo}/issues/{issue_number}/timeline"},
+	{ID: "issues/list-labels-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/labels"},
+	{ID: "issues/get-label", Method: "GET", Path: "/repos/{owner}/{repo}/labels/{name}"},
+	{ID: "repos/list-languages", Method: "GET", Path: "/repos/{owner}/{repo}/languages"},
+	{ID: "licenses/get-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/license"},
+	{ID: "issues/list-milestones", Method: "GET", Path: "/repos/{owner}/{repo}/milestones"},
+	{ID: "issues/get-milestone", Method: "GET", Path: "/repos/{owner}/{repo}/milestones/{milestone_number}"},
+	{ID: "repos/get-pages", Method: "GET", Path: "/repos/{owner}/{repo}/pages"},
+	{ID: "pulls/list", Method: "GET", Path: "/repos/{owner}/{repo}/pulls"},
+	{ID: "pulls/create", Method: "POST", Path: "/repos/{owner}/{repo}/pulls"},
+	{ID: "pulls/get", Method: "GET", Path: "/repos/{owner}/{repo}/pulls/{pull_number}"},
+	{ID: "pulls/update", Method: "PATCH", Path: "/repos/{owner}/{repo}/pulls/{pull_number}"},
+	{ID: "pulls/list-review-comments", Method: "GET", Path: "/repos/{owner}/{repo}/pulls/{pull_number}/comments"},
+	{ID: "pulls/list-commits", Method: "GET", Path: "/repos/{owner}/{repo}/pulls/{pull_number}/commits"},
+	{ID: "pulls/list-files", Method: "GET", Path: "/repos/{owner}/{repo}/pulls/{pull_number}/files"},
+	{ID: "pulls/check-if-merged", Method: "GET", Path: "/repos/{owner}/{repo}/pulls/{pull_number}/merge"},
+	{ID: "pulls/merge", Method: "PUT", Path: "/repos/{owner}/{repo}/pulls/{pull_number}/merge"},
+	{ID: "pulls/list-requested-reviewers", Method: "GET", Path: "/repos/{owner}/{repo}/pulls/{pull_number}/requested_reviewers"},
+	{ID: "pulls/list-reviews", Method: "GET", Path: "/repos/{owner}/{repo}/pulls/{pull_number}/reviews"},
+	{ID: "repos/get-readme", Method: "GET", Path: "/repos/{owner}/{repo}/readme"},
+	{ID: "repos/list-releases", Method: "GET", Path: "/repos/{owner}/{repo}/releases"},
+	{ID: "repos/get-latest-release", Method: "GET", Path: "/repos/{owner}/{repo}/releases/latest"},
+	{ID: "repos/get-release-by-tag", Method: "GET", Path: "/repos/{owner}/{repo}/releases/tags/{tag}"},
+	{ID: "repos/get-release", Method: "GET", Path: "/repos/{owner}/{repo}/releases/{release_id}"},
+	{ID: "repos/list-release-assets", Method: "GET", Path: "/repos/{owner}/{repo}/releases/{release_id}/assets"},
+	{ID: "secret-scanning/list-alerts-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/secret-scanning/alerts"},
+	{ID: "activity/list-stargazers-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/stargazers"},
+	{ID: "activity/list-watchers-for-repo", Method: "GET", Path: "/repos/{owner}/{repo}/subscribers"},
+	{ID: "repos/list-tags", Method: "GET", Path: "/repos/{owner}/{repo}/tags"},
+	{ID: "repos/list-teams", Method: "GET", Path: "/repos/{owner}/{repo}/teams"},
+	{ID: "repos/get-all-topics", Method: "GET", Path: "/repos/{owner}/{repo}/topics"},
+	{ID: "search/code", Method: "GET", Path: "/search/code"},
+	{ID: "search/commits", Method: "GET", Path: "/search/commits"},
+	{ID: "search/issues-and-pull-requests", Method: "GET", Path: "/search/issues"},
+	{ID: "search/labels", Method: "GET", Path: "/search/labels"},
+	{ID: "search/repos", Method: "GET", Path: "/search/repositories"},
+	{ID: "search/topics", Method: "GET", Path: "/search/topics"},
+	{ID: "search/users", Method: "GET", Path: "/search/users"},
+	{ID: "users/get-authenticated", Method: "GET", Path: "/user"},
+	{ID: "issues/list-for-authenticated-user", Method: "GET", Path: "/user/issues"},
+	{ID: "orgs/list-for-authenticated-user", Method: "GET", Path: "/user/orgs"},
+	{ID: "repos/list-for-authenticated-user", Method: "GET", Path: "/user/repos"},
+	{ID: "users/get-by-username", Method: "GET", Path: "/users/{username}"},
+	{ID: "activity/list-public-events-for-user", Method: "GET", Path: "/users/{username}/events/public"},
+	{ID: "gists/list-for-user", Method: "GET", Path: "/users/{username}/gists"},
+	{ID: "orgs/list-for-user", Method: "GET", Path: "/users/{username}/orgs"},
+	{ID: "repos/list-for-user", Method: "GET", Path: "/users/{username}/repos"},
+	{ID: "meta/get-zen", Method: "GET", Path: "/zen"},
+}
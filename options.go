@@ -0,0 +1,142 @@
+package ghtransport
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Options configures a Transport. Any zero-valued field falls back to the corresponding package-level
+// default (ex: CacheName, VaryHeaders) as it was at the time New was called.
+type Options struct {
+	// Storage is used to read/write cached responses. A nil Storage is safe to use: nothing is ever read
+	// from or written to it, but the speculative ETag guesses still apply.
+	Storage Storage
+	// PrivateStorage, if set, is a private-safe Storage (ex: a local encrypted database) keeping the responses
+	// marked "Cache-Control: private", Storage is then treated as shared between principals (ex: an S3 bucket)
+	// and only keeps the other responses. Responses are read from PrivateStorage first.
+	PrivateStorage Storage
+	// Parent performs the upstream requests, defaults to http.DefaultTransport.
+	Parent http.RoundTripper
+	// CacheName identifies this cache in the "Cache-Status" header, defaults to CacheName.
+	CacheName string
+	// VaryHeaders are the headers used to calculate the ETag, defaults to VaryHeaders. It must remain sorted.
+	VaryHeaders []string
+	// VaryPrefix is the prefix used to store the "Vary" request headers in storage, defaults to VaryPrefix.
+	VaryPrefix string
+	// UserAgentReplacer rewrites the "User-Agent" header to avoid pretty-printed responses, defaults to UserAgentReplacer.
+	UserAgentReplacer *strings.Replacer
+	// StorageErrorPolicy determines whether Storage errors fail the request (FailClosed, the default)
+	// or degrade to a plain upstream request (FailOpen).
+	StorageErrorPolicy StorageErrorPolicy
+	// OnStorageError, if set, is called with a *StorageError whenever a Storage operation fails,
+	// regardless of the StorageErrorPolicy.
+	OnStorageError func(*http.Request, error)
+	// StaleIfError, if positive, is how long after its last successful validation (or store) a cached
+	// response may be served when the upstream request fails with an error or a 5xx status code. The
+	// "Cache-Status" header of such a response includes "detail=stale-if-error".
+	StaleIfError time.Duration
+	// RevalidateTimeout, if positive, bounds how long a request with a cached response waits for
+	// revalidation. If it takes longer, the cached response is returned immediately (with a "Cache-Status"
+	// of "detail=stale-while-revalidate") while the revalidation, including storing a modified
+	// response, continues in the background.
+	RevalidateTimeout time.Duration
+	// Coalesce enables in-process coalescing of concurrent identical cacheable requests (same method,
+	// URL, VaryHeaders and conditional headers), such that only one upstream request is in-flight and the
+	// others share its response. The "Cache-Status" header of the shared responses includes the "collapsed"
+	// parameter.
+	Coalesce bool
+	// LeaseWait, if positive and the Storage implements Leaser, enables coordinating revalidation with
+	// other processes sharing the Storage. If another process holds the lease for a URL, the request
+	// waits up to LeaseWait for it to be released, then serves the stored response if it was revalidated
+	// (or stored) meanwhile, otherwise it revalidates the stored response itself.
+	LeaseWait time.Duration
+	// MaxVariants, if greater than 1, is the number of variants stored per URL, each keyed by the
+	// X-Varied-* headers it was stored with (ex: tokens with different permissions that see different
+	// response bodies). The variant matching a request exactly is used in place of the most recently
+	// stored response. Evicted variants are deleted if the Storage implements Deleter.
+	MaxVariants int
+	// Speculations are the speculative response bodies guessed for requests with no (matching) cached
+	// response, defaults to Speculations. Set it to an empty (non-nil) slice to disable guessing entirely.
+	Speculations []Speculation
+	// TTL, if positive, is the default freshness lifetime of a stored response: it is served without
+	// revalidation until TTL after it was stored. The "Cache-Status" header of such a response includes the
+	// remaining "ttl" (in seconds).
+	TTL time.Duration
+	// MaxAge uses the "max-age" directive of the stored "Cache-Control" response header (ex: GitHub's
+	// "private, max-age=60") as the freshness lifetime, in place of TTL.
+	MaxAge bool
+	// OnlyIfCached answers every request only from the Storage, never calling Parent (ex: replaying a
+	// pre-populated cache without network access). A request without a usable stored response gets a
+	// synthetic "504 Gateway Timeout", per RFC 9111 "only-if-cached". See WithOnlyIfCached for a single request.
+	OnlyIfCached bool
+	// Routes is the policy table, matched in order against the request path, defaults to Routes. Requests
+	// matching no Route use the zero Route (cached, revalidated on every request). "/rate_limit" is always
+	// bypassed.
+	Routes []Route
+	// OnRateLimit, if set, is called whenever an upstream response changes the rate limit state of its
+	// principal and resource (see (*Transport).RateLimits). It must not block.
+	OnRateLimit func(RateLimit)
+	// Pace spreads the upstream requests that will likely count against the rate limit (ex: no stored
+	// response to revalidate) evenly over the remaining window, per the most recently observed rate limit
+	// state of their principal and resource. Once the rate limit is exhausted, every upstream request is
+	// handled per the QuotaPolicy.
+	Pace bool
+	// QuotaPolicy determines whether a request waits for the rate limit to reset (QuotaWait, the default)
+	// or fails with a *RateLimitError (QuotaFailFast) once the rate limit is exhausted, see Pace.
+	QuotaPolicy QuotaPolicy
+	// Backoff holds back the upstream requests of a principal serving a secondary rate limit Penalty (a 403
+	// or 429 response with "Retry-After", an exhausted rate limit or a "secondary rate limit" message) until
+	// it ends, or fails them with a *PenaltyError per the QuotaPolicy. Penalties are tracked regardless, see
+	// (*Transport).Penalties.
+	Backoff bool
+	// StaleIfPenalty serves the cached response (if any) in place of a response imposing a Penalty and, with
+	// Backoff, in place of the upstream requests during the Penalty. The "Cache-Status" header of such a
+	// response includes "detail=stale-if-penalty".
+	StaleIfPenalty bool
+	// Budgets limits the upstream requests (not answered with a 304 Not Modified) of each caller, keyed by
+	// the identity the requests are tagged with (see WithCaller). Once exhausted, a request is served stale
+	// (with a "Cache-Status" of "detail=stale-if-over-budget") if a matching response is stored, otherwise
+	// it fails with a *BudgetError. Untagged callers and callers without a Budget are not limited.
+	Budgets map[string]Budget
+	// PersistHeaders determines which upstream response headers are persisted by Storage.Put, defaults to
+	// DefaultHeaderPolicy (dropping the PrincipalHeaders). The "Etag", "Vary" and "Cache-Control" headers
+	// are always persisted.
+	PersistHeaders HeaderPolicy
+	// ReplayHeaders determines which stored response headers are returned when answering from the cache
+	// (including after a 304 Not Modified), defaults to DefaultHeaderPolicy. It also covers responses stored
+	// before PersistHeaders was tightened.
+	ReplayHeaders HeaderPolicy
+}
+
+// withDefaults returns a copy of the Options with any zero-valued fields replaced by their defaults.
+func (o Options) withDefaults() Options {
+	if o.Parent == nil {
+		o.Parent = http.DefaultTransport
+	}
+	if o.CacheName == "" {
+		o.CacheName = CacheName
+	}
+	if o.VaryHeaders == nil {
+		o.VaryHeaders = VaryHeaders
+	}
+	if o.VaryPrefix == "" {
+		o.VaryPrefix = VaryPrefix
+	}
+	if o.Speculations == nil {
+		o.Speculations = Speculations
+	}
+	if o.Routes == nil {
+		o.Routes = Routes
+	}
+	if o.PersistHeaders.isZero() {
+		o.PersistHeaders = DefaultHeaderPolicy
+	}
+	if o.ReplayHeaders.isZero() {
+		o.ReplayHeaders = DefaultHeaderPolicy
+	}
+	if o.UserAgentReplacer == nil {
+		o.UserAgentReplacer = UserAgentReplacer
+	}
+	return o
+}
@@ -0,0 +1,114 @@
+package ghtransport
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuotaPolicy determines how a pacing Transport (see Options.Pace) handles an upstream request once the rate
+// limit of its principal and resource is exhausted.
+type QuotaPolicy int
+
+const (
+	// QuotaWait blocks the request until the rate limit resets (or its context is done), this is the default.
+	QuotaWait QuotaPolicy = iota
+	// QuotaFailFast immediately returns a *RateLimitError from RoundTrip.
+	QuotaFailFast
+)
+
+// RateLimitError is returned by RoundTrip when the rate limit of the request's principal and resource is
+// exhausted and the QuotaPolicy is QuotaFailFast.
+type RateLimitError struct {
+	RateLimit RateLimit
+}
+
+func (e *RateLimitError) Error() string {
+	return "rate limit of " + e.RateLimit.Resource + " exhausted until " + e.RateLimit.Reset.Format(time.RFC3339)
+}
+
+// pacer holds, per principal and resource, the earliest time the next paced upstream request may be sent.
+type pacer struct {
+	mu   sync.Mutex
+	next map[rateLimitKey]time.Time
+}
+
+// rateLimitResource determines the rate limit resource (see RateLimit.Resource) a request counts against.
+func rateLimitResource(req *http.Request) string {
+	var path string
+	if req.URL != nil {
+		path = strings.TrimPrefix(req.URL.Path, "/api/v3")
+	}
+	switch {
+	case strings.HasPrefix(path, "/search/code"):
+		return "code_search"
+	case strings.HasPrefix(path, "/search/"):
+		return "search"
+	case path == "/graphql":
+		return "graphql"
+	default:
+		return "core"
+	}
+}
+
+// pace delays (see Options.Pace) an upstream request per the most recently observed rate limit state of its
+// principal and resource. Once exhausted, every request waits for the reset or fails per the QuotaPolicy.
+// Otherwise only the requests that are expected to count against the rate limit (paced) are spread evenly
+// over the remaining window, revalidations (likely a free 304 Not Modified) are sent immediately.
+func (t *Transport) pace(req *http.Request, paced bool) error {
+	if !t.opts.Pace {
+		return nil
+	}
+	key := rateLimitKey{principal: HashToken(req.Header.Get("Authorization")), resource: rateLimitResource(req)}
+	t.rateLimits.mu.Lock()
+	limit, ok := t.rateLimits.limits[key]
+	t.rateLimits.mu.Unlock()
+	now := t.now()
+	if !ok || !now.Before(limit.Reset) {
+		return nil // Nothing known about the current window (yet)
+	}
+
+	if limit.Remaining <= 0 {
+		if t.opts.QuotaPolicy == QuotaFailFast {
+			return &RateLimitError{RateLimit: limit}
+		}
+		return t.sleep(req.Context(), limit.Reset.Sub(now))
+	}
+	if !paced {
+		return nil
+	}
+
+	// Reserve the next slot, such that the remaining requests last until the reset
+	interval := limit.Reset.Sub(now) / time.Duration(limit.Remaining)
+	t.pacer.mu.Lock()
+	slot := now
+	if next := t.pacer.next[key]; next.After(slot) {
+		slot = next
+	}
+	if slot.After(limit.Reset) {
+		slot = limit.Reset
+	}
+	if t.pacer.next == nil {
+		t.pacer.next = make(map[rateLimitKey]time.Time)
+	}
+	t.pacer.next[key] = slot.Add(interval)
+	t.pacer.mu.Unlock()
+	return t.sleep(req.Context(), slot.Sub(now))
+}
+
+// sleep waits for the duration, returning early with the context's error if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
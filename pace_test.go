@@ -0,0 +1,194 @@
+package ghtransport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitResource(t *testing.T) {
+	tests := []struct {
+		Path     string
+		Expected string
+	}{
+		{Path: "/repos/foo/bar", Expected: "core"},
+		{Path: "/search/issues", Expected: "search"},
+		{Path: "/search/code", Expected: "code_search"},
+		{Path: "/graphql", Expected: "graphql"},
+		{Path: "/api/v3/search/issues", Expected: "search"},
+		{Path: "/api/graphql", Expected: "core"},
+	}
+	for _, test := range tests {
+		t.Run(test.Path, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com"+test.Path, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if got := rateLimitResource(req); got != test.Expected {
+				t.Errorf("rateLimitResource() = %q, want %q", got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_Pace(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	reset := now.Add(100 * time.Second)
+	cachedResp := func() *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": []string{`"tag1"`}},
+			Body:       io.NopCloser(strings.NewReader("content")),
+		}
+	}
+
+	tests := []struct {
+		Name      string
+		Policy    QuotaPolicy
+		Remaining int
+		Known     bool
+		Requests  []string // "uncached", "cached", "post" or "bypass"
+		Sleeps    []time.Duration
+		Upstream  int
+		Err       bool
+	}{
+		{
+			Name:     "unknown",
+			Requests: []string{"uncached", "uncached"},
+			Upstream: 2,
+		},
+		{
+			Name:      "spread",
+			Known:     true,
+			Remaining: 10,
+			Requests:  []string{"uncached", "uncached", "post", "uncached"},
+			Sleeps:    []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second},
+			Upstream:  4,
+		},
+		{
+			Name:      "revalidations are not paced",
+			Known:     true,
+			Remaining: 10,
+			Requests:  []string{"uncached", "cached", "cached", "uncached"},
+			Sleeps:    []time.Duration{10 * time.Second},
+			Upstream:  4,
+		},
+		{
+			Name:      "exhausted wait",
+			Known:     true,
+			Remaining: 0,
+			Requests:  []string{"cached", "uncached"},
+			Sleeps:    []time.Duration{100 * time.Second, 100 * time.Second},
+			Upstream:  2,
+		},
+		{
+			Name:      "exhausted fail fast",
+			Policy:    QuotaFailFast,
+			Known:     true,
+			Remaining: 0,
+			Requests:  []string{"cached"},
+			Err:       true,
+		},
+		{
+			Name:      "exhausted bypass",
+			Policy:    QuotaFailFast,
+			Known:     true,
+			Remaining: 0,
+			Requests:  []string{"bypass"},
+			Upstream:  1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			upstream := 0
+			tr := New(Options{
+				Storage: &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						if req.URL.Path == "/repos/foo/cached" {
+							return cachedResp(), nil
+						}
+						return nil, nil
+					},
+				},
+				Parent: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						upstream++
+						return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: http.NoBody}, nil
+					},
+				},
+				Pace:         true,
+				QuotaPolicy:  test.Policy,
+				Speculations: []Speculation{},
+			})
+			tr.now = func() time.Time { return now }
+			var sleeps []time.Duration
+			tr.sleep = func(ctx context.Context, d time.Duration) error {
+				if d > 0 {
+					sleeps = append(sleeps, d)
+				}
+				return nil
+			}
+			if test.Known {
+				tr.rateLimits.limits = map[rateLimitKey]RateLimit{
+					{principal: HashToken("Bearer token"), resource: "core"}: {
+						Resource:  "core",
+						Limit:     5000,
+						Remaining: test.Remaining,
+						Reset:     reset,
+					},
+				}
+			}
+
+			var err error
+			for _, kind := range test.Requests {
+				method, path := http.MethodGet, "/repos/foo/"+kind
+				switch kind {
+				case "post":
+					method = http.MethodPost
+				case "bypass":
+					path = "/rate_limit"
+				}
+				req, reqErr := http.NewRequest(method, "https://api.github.com"+path, nil)
+				if reqErr != nil {
+					t.Fatalf("failed to create request: %v", reqErr)
+				}
+				req.Header.Set("Authorization", "Bearer token")
+				var resp *http.Response
+				if resp, err = tr.RoundTrip(req); err != nil {
+					break
+				}
+				resp.Body.Close()
+			}
+
+			var rlErr *RateLimitError
+			if got := errors.As(err, &rlErr); got != test.Err {
+				t.Fatalf("RoundTrip() error = %v, want *RateLimitError %v", err, test.Err)
+			}
+			if rlErr != nil && !rlErr.RateLimit.Reset.Equal(reset) {
+				t.Errorf("RateLimitError.RateLimit.Reset = %v, want %v", rlErr.RateLimit.Reset, reset)
+			}
+			if !reflect.DeepEqual(sleeps, test.Sleeps) {
+				t.Errorf("RoundTrip() slept %v, want %v", sleeps, test.Sleeps)
+			}
+			if upstream != test.Upstream {
+				t.Errorf("RoundTrip() made %d upstream requests, want %d", upstream, test.Upstream)
+			}
+		})
+	}
+}
+
+func TestSleep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("sleep() error = %v, want %v", err, context.Canceled)
+	}
+	if err := sleep(ctx, 0); err != nil {
+		t.Errorf("sleep() of zero error = %v, want nil", err)
+	}
+}
@@ -55,6 +55,14 @@ func (s *Storage) Put(ctx context.Context, resp *http.Response) error {
 	return nil
 }
 
+func (s *Storage) Delete(ctx context.Context, req *http.Request) error {
+	key := []byte(req.URL.String())
+	if err := s.DB.Delete(key, s.WriteOptions); err != nil {
+		return fmt.Errorf("(*pebble.DB).Delete failed: %w", err)
+	}
+	return nil
+}
+
 // Open is a wrapper around pebble.Open that returns an initialized Storage.
 func Open(path string, opts *pebble.Options) (*Storage, error) {
 	db, err := pebble.Open(path, opts)
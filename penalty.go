@@ -0,0 +1,183 @@
+package ghtransport
+
+import (
+	"bytes"
+	"cmp"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Penalty is a secondary rate limit (or an exhausted primary rate limit) imposed on a principal, during which
+// further requests would be rejected by GitHub.
+type Penalty struct {
+	// Principal is the hashed token (see HashToken) of the principal.
+	Principal string
+	// Resource is the rate limit resource (ex: "core", "search") of an exhausted primary rate limit, empty for a
+	// secondary rate limit as it applies to every request of the principal.
+	Resource string
+	// Until is when the penalty ends.
+	Until time.Time
+	// StatusCode is the status code of the response that imposed the penalty (403 or 429).
+	StatusCode int
+	// Reason is how the end of the penalty was determined: "retry-after", "rate-limit-reset" or
+	// "secondary-rate-limit" (no explicit time given, one minute per GitHub's documentation).
+	Reason string
+}
+
+// PenaltyError is returned by RoundTrip when the request's principal is serving a Penalty, the QuotaPolicy
+// is QuotaFailFast and no stale response may be served instead (see Options.Backoff).
+type PenaltyError struct {
+	Penalty Penalty
+}
+
+func (e *PenaltyError) Error() string {
+	return "secondary rate limit (" + e.Penalty.Reason + ") until " + e.Penalty.Until.Format(time.RFC3339)
+}
+
+// penalties tracks the active Penalty of each principal (and resource) seen by a Transport.
+type penalties struct {
+	mu        sync.Mutex
+	penalties map[rateLimitKey]Penalty
+}
+
+// secondaryRateLimitBody is how much of a 403 response body is searched for the "secondary rate limit" message.
+const secondaryRateLimitBody = 4096
+
+// parsePenalty determines if the response imposes a Penalty, per GitHub's guidance for handling rate
+// limits: honor "Retry-After", else wait until "X-Ratelimit-Reset" if no requests remain, else wait at least
+// a minute if the body mentions a secondary rate limit. The body is restored after being inspected, any
+// error reading it is left for the caller to encounter.
+func parsePenalty(resp *http.Response, now time.Time) (_ Penalty, ok bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return Penalty{}, false
+	}
+	penalty := Penalty{StatusCode: resp.StatusCode}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		penalty.Until, penalty.Reason = now.Add(time.Duration(seconds)*time.Second), "retry-after"
+		return penalty, true
+	}
+	if resp.Header.Get("X-Ratelimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			penalty.Until, penalty.Reason = time.Unix(reset, 0).UTC(), "rate-limit-reset"
+			return penalty, true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		penalty.Until, penalty.Reason = now.Add(time.Minute), "secondary-rate-limit"
+		return penalty, true
+	}
+	if resp.Body == nil {
+		return Penalty{}, false
+	}
+
+	// A 403 may also be a permission error, only the message tells them apart
+	prefix, _ := io.ReadAll(io.LimitReader(resp.Body, secondaryRateLimitBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), resp.Body), resp.Body}
+	if !strings.Contains(strings.ToLower(string(prefix)), "secondary rate limit") {
+		return Penalty{}, false
+	}
+	penalty.Until, penalty.Reason = now.Add(time.Minute), "secondary-rate-limit"
+	return penalty, true
+}
+
+// notePenalty records the Penalty imposed by the upstream response to the request (if any), reporting if it
+// imposed one. An exhausted primary rate limit only applies to its resource, as reported by the response.
+// A shorter penalty never replaces a longer one.
+func (t *Transport) notePenalty(req *http.Request, resp *http.Response) bool {
+	penalty, ok := parsePenalty(resp, t.now())
+	if !ok {
+		return false
+	}
+	penalty.Principal = HashToken(req.Header.Get("Authorization"))
+	if penalty.Reason == "rate-limit-reset" {
+		if penalty.Resource = resp.Header.Get("X-Ratelimit-Resource"); penalty.Resource == "" {
+			penalty.Resource = rateLimitResource(req)
+		}
+	}
+	key := rateLimitKey{principal: penalty.Principal, resource: penalty.Resource}
+
+	t.penalties.mu.Lock()
+	defer t.penalties.mu.Unlock()
+	if prev, ok := t.penalties.penalties[key]; ok && prev.Until.After(penalty.Until) {
+		return true
+	}
+	if t.penalties.penalties == nil {
+		t.penalties.penalties = make(map[rateLimitKey]Penalty)
+	}
+	t.penalties.penalties[key] = penalty
+	return true
+}
+
+// Penalty returns the Penalty the principal (identified by its "Authorization" header) is serving for the
+// rate limit resource, either a secondary rate limit or an exhausted primary rate limit of that resource
+// (whichever ends last), ok is false if there is none.
+func (t *Transport) Penalty(authorization, resource string) (_ Penalty, ok bool) {
+	principal := HashToken(authorization)
+	now := t.now()
+	t.penalties.mu.Lock()
+	defer t.penalties.mu.Unlock()
+	var found Penalty
+	for _, key := range []rateLimitKey{{principal: principal}, {principal: principal, resource: resource}} {
+		penalty, seen := t.penalties.penalties[key]
+		if seen && now.Before(penalty.Until) && (!ok || penalty.Until.After(found.Until)) {
+			found, ok = penalty, true
+		}
+	}
+	return found, ok
+}
+
+// Penalties returns a snapshot of the active Penalty of every principal and resource, sorted by principal
+// then resource.
+func (t *Transport) Penalties() []Penalty {
+	now := t.now()
+	t.penalties.mu.Lock()
+	var active []Penalty
+	for _, penalty := range t.penalties.penalties {
+		if now.Before(penalty.Until) {
+			active = append(active, penalty)
+		}
+	}
+	t.penalties.mu.Unlock()
+	slices.SortFunc(active, func(a, b Penalty) int {
+		return cmp.Or(cmp.Compare(a.Principal, b.Principal), cmp.Compare(a.Resource, b.Resource))
+	})
+	return active
+}
+
+// backoff holds an upstream request back while its principal is serving a Penalty for the request's rate limit
+// resource (see Options.Backoff): the cached response is returned as stale (if allowed by
+// Options.StaleIfPenalty), otherwise it waits for the penalty to end or fails per the QuotaPolicy.
+func (t *Transport) backoff(req *http.Request, cached *http.Response) (*http.Response, error) {
+	if !t.opts.Backoff {
+		return nil, nil
+	}
+	penalty, ok := t.Penalty(req.Header.Get("Authorization"), rateLimitResource(req))
+	if !ok {
+		return nil, nil
+	}
+	if stale := t.staleIfPenalty(req, cached); stale != nil {
+		return stale, nil
+	}
+	if t.opts.QuotaPolicy == QuotaFailFast {
+		return nil, &PenaltyError{Penalty: penalty}
+	}
+	return nil, t.sleep(req.Context(), penalty.Until.Sub(t.now()))
+}
+
+// staleIfPenalty returns the cached response (or a 304 Not Modified if it matches the caller's validators) if it
+// may be served in place of an upstream request during a Penalty (see Options.StaleIfPenalty), otherwise it
+// returns nil.
+func (t *Transport) staleIfPenalty(req *http.Request, cached *http.Response) *http.Response {
+	if cached == nil || !t.opts.StaleIfPenalty || !t.identicalVary(req, cached) {
+		return nil
+	}
+	return t.freshResponse(req, cached, cacheStatusDetail(cacheStatusHit(t.opts.CacheName), "stale-if-penalty"))
+}
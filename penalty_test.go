@@ -0,0 +1,290 @@
+package ghtransport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePenalty(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	reset := now.Add(30 * time.Minute)
+	tests := []struct {
+		Name       string
+		StatusCode int
+		Header     http.Header
+		Body       string
+		Expected   Penalty
+		OK         bool
+	}{
+		{
+			Name:       "retry-after",
+			StatusCode: http.StatusForbidden,
+			Header:     http.Header{"Retry-After": []string{"90"}},
+			Body:       `{"message":"You have exceeded a secondary rate limit."}`,
+			Expected:   Penalty{Until: now.Add(90 * time.Second), StatusCode: http.StatusForbidden, Reason: "retry-after"},
+			OK:         true,
+		},
+		{
+			Name:       "rate-limit-reset",
+			StatusCode: http.StatusForbidden,
+			Header: http.Header{
+				"X-Ratelimit-Remaining": []string{"0"},
+				"X-Ratelimit-Reset":     []string{strconv.FormatInt(reset.Unix(), 10)},
+			},
+			Body:     `{"message":"API rate limit exceeded for user ID 1."}`,
+			Expected: Penalty{Until: reset, StatusCode: http.StatusForbidden, Reason: "rate-limit-reset"},
+			OK:       true,
+		},
+		{
+			Name:       "too many requests",
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{},
+			Expected:   Penalty{Until: now.Add(time.Minute), StatusCode: http.StatusTooManyRequests, Reason: "secondary-rate-limit"},
+			OK:         true,
+		},
+		{
+			Name:       "message",
+			StatusCode: http.StatusForbidden,
+			Header:     http.Header{"X-Ratelimit-Remaining": []string{"4000"}},
+			Body:       `{"message":"You have exceeded a Secondary Rate Limit. Please wait a few minutes before you try again."}`,
+			Expected:   Penalty{Until: now.Add(time.Minute), StatusCode: http.StatusForbidden, Reason: "secondary-rate-limit"},
+			OK:         true,
+		},
+		{
+			Name:       "permission",
+			StatusCode: http.StatusForbidden,
+			Header:     http.Header{},
+			Body:       `{"message":"Resource not accessible by integration"}`,
+		},
+		{
+			Name:       "ok",
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Retry-After": []string{"90"}},
+			Body:       `{}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: test.StatusCode,
+				Header:     test.Header,
+				Body:       io.NopCloser(strings.NewReader(test.Body)),
+			}
+			got, ok := parsePenalty(resp, now)
+			if ok != test.OK {
+				t.Fatalf("parsePenalty() ok = %v, want %v", ok, test.OK)
+			}
+			if !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("parsePenalty() = %+v, want %+v", got, test.Expected)
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			if string(body) != test.Body {
+				t.Errorf("parsePenalty() left body %q, want %q", body, test.Body)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_Backoff(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		Name        string
+		Options     Options
+		Cached      bool
+		IfNoneMatch string
+		Statuses    []int
+		Sleeps      []time.Duration
+		Upstream    int
+		Err         bool
+	}{
+		{
+			Name:     "tracked only",
+			Statuses: []int{http.StatusForbidden, http.StatusOK},
+			Upstream: 2,
+		},
+		{
+			Name:     "wait",
+			Options:  Options{Backoff: true},
+			Statuses: []int{http.StatusForbidden, http.StatusOK},
+			Sleeps:   []time.Duration{90 * time.Second},
+			Upstream: 2,
+		},
+		{
+			Name:     "fail fast",
+			Options:  Options{Backoff: true, QuotaPolicy: QuotaFailFast},
+			Statuses: []int{http.StatusForbidden, http.StatusOK},
+			Upstream: 1,
+			Err:      true,
+		},
+		{
+			Name:     "stale if penalty",
+			Options:  Options{Backoff: true, StaleIfPenalty: true},
+			Cached:   true,
+			Statuses: []int{http.StatusOK, http.StatusOK},
+			Upstream: 1,
+		},
+		{
+			Name:        "stale if penalty with a matching If-None-Match",
+			Options:     Options{Backoff: true, StaleIfPenalty: true},
+			Cached:      true,
+			IfNoneMatch: `"tag1"`,
+			Statuses:    []int{http.StatusNotModified, http.StatusNotModified},
+			Upstream:    1,
+		},
+		{
+			Name:     "stale if penalty without a cached response",
+			Options:  Options{Backoff: true, StaleIfPenalty: true, QuotaPolicy: QuotaFailFast},
+			Statuses: []int{http.StatusForbidden, http.StatusOK},
+			Upstream: 1,
+			Err:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			upstream := 0
+			opts := test.Options
+			opts.Storage = &mockStorage{
+				getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+					if !test.Cached {
+						return nil, nil
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Etag": []string{`"tag1"`}},
+						Body:       io.NopCloser(strings.NewReader("cached")),
+					}, nil
+				},
+			}
+			opts.Parent = &mockRoundTripper{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					upstream++
+					if upstream == 1 {
+						return &http.Response{
+							StatusCode: http.StatusForbidden,
+							Header:     http.Header{"Retry-After": []string{"90"}},
+							Body:       io.NopCloser(strings.NewReader(`{"message":"You have exceeded a secondary rate limit."}`)),
+						}, nil
+					}
+					return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("content"))}, nil
+				},
+			}
+			opts.Speculations = []Speculation{}
+			tr := New(opts)
+			tr.now = func() time.Time { return now }
+			var sleeps []time.Duration
+			tr.sleep = func(ctx context.Context, d time.Duration) error {
+				sleeps = append(sleeps, d)
+				return nil
+			}
+
+			var err error
+			for idx, want := range test.Statuses {
+				req, reqErr := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+				if reqErr != nil {
+					t.Fatalf("failed to create request: %v", reqErr)
+				}
+				req.Header.Set("Authorization", "Bearer token")
+				if test.IfNoneMatch != "" {
+					req.Header.Set("If-None-Match", test.IfNoneMatch)
+				}
+				var resp *http.Response
+				if resp, err = tr.RoundTrip(req); err != nil {
+					break
+				}
+				resp.Body.Close()
+				if resp.StatusCode != want {
+					t.Errorf("RoundTrip() #%d status = %d, want %d", idx, resp.StatusCode, want)
+				}
+				if test.Cached {
+					if got, want := resp.Header.Get("Cache-Status"), withOperation(cacheStatusDetail(cacheStatusHit(CacheName), "stale-if-penalty")); got != want {
+						t.Errorf("RoundTrip() #%d Cache-Status = %q, want %q", idx, got, want)
+					}
+				}
+			}
+
+			var pErr *PenaltyError
+			if got := errors.As(err, &pErr); got != test.Err {
+				t.Fatalf("RoundTrip() error = %v, want *PenaltyError %v", err, test.Err)
+			}
+			if !reflect.DeepEqual(sleeps, test.Sleeps) {
+				t.Errorf("RoundTrip() slept %v, want %v", sleeps, test.Sleeps)
+			}
+			if upstream != test.Upstream {
+				t.Errorf("RoundTrip() made %d upstream requests, want %d", upstream, test.Upstream)
+			}
+
+			want := Penalty{Principal: HashToken("Bearer token"), Until: now.Add(90 * time.Second), StatusCode: http.StatusForbidden, Reason: "retry-after"}
+			if got, ok := tr.Penalty("Bearer token", "core"); !ok || got != want {
+				t.Errorf("Penalty() = %+v, %v, want %+v", got, ok, want)
+			}
+			if got := tr.Penalties(); !reflect.DeepEqual(got, []Penalty{want}) {
+				t.Errorf("Penalties() = %+v, want %+v", got, []Penalty{want})
+			}
+			now = now.Add(90 * time.Second)
+			if _, ok := tr.Penalty("Bearer token", "core"); ok {
+				t.Error("Penalty() after it ended ok = true, want false")
+			}
+			now = now.Add(-90 * time.Second)
+		})
+	}
+}
+
+func TestTransport_Penalty_Resource(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	reset := now.Add(30 * time.Minute)
+	tr := NewTransport(nil, nil)
+	tr.now = func() time.Time { return now }
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/search/issues", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	exhausted := &http.Response{
+		StatusCode: http.StatusForbidden,
+		Header: http.Header{
+			"X-Ratelimit-Remaining": []string{"0"},
+			"X-Ratelimit-Reset":     []string{strconv.FormatInt(reset.Unix(), 10)},
+			"X-Ratelimit-Resource":  []string{"search"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"message":"API rate limit exceeded for user ID 1."}`)),
+	}
+	if !tr.notePenalty(req, exhausted) {
+		t.Fatal("notePenalty() = false, want true")
+	}
+
+	// An exhausted primary rate limit only applies to its resource
+	want := Penalty{Principal: HashToken("Bearer token"), Resource: "search", Until: reset, StatusCode: http.StatusForbidden, Reason: "rate-limit-reset"}
+	if got, ok := tr.Penalty("Bearer token", "search"); !ok || got != want {
+		t.Errorf("Penalty(search) = %+v, %v, want %+v", got, ok, want)
+	}
+	if got, ok := tr.Penalty("Bearer token", "core"); ok {
+		t.Errorf("Penalty(core) = %+v, want none", got)
+	}
+
+	// A secondary rate limit applies to every resource
+	secondary := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}, Body: http.NoBody}
+	if !tr.notePenalty(req, secondary) {
+		t.Fatal("notePenalty() = false, want true")
+	}
+	wantSecondary := Penalty{Principal: HashToken("Bearer token"), Until: now.Add(time.Minute), StatusCode: http.StatusTooManyRequests, Reason: "secondary-rate-limit"}
+	if got, ok := tr.Penalty("Bearer token", "core"); !ok || got != wantSecondary {
+		t.Errorf("Penalty(core) = %+v, %v, want %+v", got, ok, wantSecondary)
+	}
+	if got, ok := tr.Penalty("Bearer token", "search"); !ok || got != want {
+		t.Errorf("Penalty(search) = %+v, %v, want %+v", got, ok, want)
+	}
+	if got := tr.Penalties(); !reflect.DeepEqual(got, []Penalty{wantSecondary, want}) {
+		t.Errorf("Penalties() = %+v, want %+v", got, []Penalty{wantSecondary, want})
+	}
+}
@@ -0,0 +1,91 @@
+package ghtransport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// private reports if the response is marked "Cache-Control: private", i.e. it is intended for a single user
+// and must not be stored by a shared cache, per RFC 9111 5.2.2.7.
+func private(headers http.Header) bool {
+	_, ok := cacheControl(headers)["private"]
+	return ok
+}
+
+// noStore reports if the response is marked "Cache-Control: no-store", i.e. it must not be stored by any
+// cache, per RFC 9111 5.2.2.5.
+func noStore(headers http.Header) bool {
+	_, ok := cacheControl(headers)["no-store"]
+	return ok
+}
+
+// storable decides if the upstream response may be stored per its "Cache-Control" header, returning the
+// "Cache-Status" detail explaining the decision (if any).
+func (t *Transport) storable(resp *http.Response) (bool, string) {
+	if noStore(resp.Header) {
+		return false, "no-store"
+	}
+	if tiered, ok := t.opts.Storage.(*tieredStorage); ok {
+		if private(resp.Header) {
+			return true, "private" // Only kept in the PrivateStorage
+		}
+		return tiered.shared != nil, ""
+	}
+	return true, ""
+}
+
+// tieredStorage keeps "private" responses in a private-safe Storage and all others in a (possibly nil) Storage
+// shared between principals, see Options.PrivateStorage. Responses are read from the private Storage first.
+type tieredStorage struct {
+	private Storage
+	shared  Storage
+}
+
+// Get implements the Storage interface.
+func (s *tieredStorage) Get(ctx context.Context, req *http.Request) (*http.Response, error) {
+	resp, err := s.private.Get(ctx, req)
+	if err != nil || resp != nil || s.shared == nil {
+		return resp, err
+	}
+	return s.shared.Get(ctx, req)
+}
+
+// Put implements the Storage interface. A response that is no longer private is removed from the private
+// Storage (if it implements Deleter), such that the outdated private response is not read in its place.
+func (s *tieredStorage) Put(ctx context.Context, resp *http.Response) error {
+	if private(resp.Header) {
+		return s.private.Put(ctx, resp)
+	}
+	if s.shared == nil {
+		return nil // See (*Transport).storable
+	}
+	if err := s.shared.Put(ctx, resp); err != nil {
+		return err
+	}
+	if deleter, ok := s.private.(Deleter); ok {
+		return deleter.Delete(ctx, resp.Request)
+	}
+	return nil
+}
+
+// Delete implements the Deleter interface, deleting from each Storage that implements it.
+func (s *tieredStorage) Delete(ctx context.Context, req *http.Request) error {
+	var errs []error
+	for _, storage := range []Storage{s.private, s.shared} {
+		if deleter, ok := storage.(Deleter); ok {
+			errs = append(errs, deleter.Delete(ctx, req))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// leaser returns the Leaser of the Storage (for a tieredStorage, that of the shared Storage), if any.
+func (t *Transport) leaser() (Leaser, bool) {
+	if tiered, ok := t.opts.Storage.(*tieredStorage); ok {
+		leaser, ok := tiered.shared.(Leaser)
+		return leaser, ok
+	}
+	leaser, ok := t.opts.Storage.(Leaser)
+	return leaser, ok
+}
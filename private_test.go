@@ -0,0 +1,187 @@
+package ghtransport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// mapStorage is a minimal in-memory Storage (and Deleter) keyed by URL, keeping only the response headers.
+type mapStorage map[string]http.Header
+
+func (m mapStorage) Get(ctx context.Context, req *http.Request) (*http.Response, error) {
+	header, ok := m[req.URL.String()]
+	if !ok {
+		return nil, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header.Clone(),
+		Body:       io.NopCloser(strings.NewReader("content")),
+	}, nil
+}
+
+func (m mapStorage) Put(ctx context.Context, resp *http.Response) error {
+	m[resp.Request.URL.String()] = resp.Header.Clone()
+	return nil
+}
+
+func (m mapStorage) Delete(ctx context.Context, req *http.Request) error {
+	delete(m, req.URL.String())
+	return nil
+}
+
+func TestTransport_RoundTrip_ResponseCacheControl(t *testing.T) {
+	tests := []struct {
+		Name         string
+		CacheControl string
+		Private      bool
+		Shared       bool
+		InShared     bool
+		InPrivate    bool
+		Expected     string
+	}{
+		{
+			Name:         "no-store",
+			CacheControl: "no-store",
+			Shared:       true,
+			Expected:     cacheStatusDetail(cacheStatusForward(CacheName, "uri-miss", http.StatusOK, false), "no-store"),
+		},
+		{
+			Name:         "no-store with private storage",
+			CacheControl: "private, no-store",
+			Private:      true,
+			Shared:       true,
+			Expected:     cacheStatusDetail(cacheStatusForward(CacheName, "uri-miss", http.StatusOK, false), "no-store"),
+		},
+		{
+			Name:         "private without private storage",
+			CacheControl: "private, max-age=60",
+			Shared:       true,
+			InShared:     true,
+			Expected:     cacheStatusForward(CacheName, "uri-miss", http.StatusOK, true),
+		},
+		{
+			Name:         "private",
+			CacheControl: "private, max-age=60",
+			Private:      true,
+			Shared:       true,
+			InPrivate:    true,
+			Expected:     cacheStatusDetail(cacheStatusForward(CacheName, "uri-miss", http.StatusOK, true), "private"),
+		},
+		{
+			Name:         "public",
+			CacheControl: "public, max-age=60",
+			Private:      true,
+			Shared:       true,
+			InShared:     true,
+			Expected:     cacheStatusForward(CacheName, "uri-miss", http.StatusOK, true),
+		},
+		{
+			Name:         "public without shared storage",
+			CacheControl: "public, max-age=60",
+			Private:      true,
+			Expected:     cacheStatusForward(CacheName, "uri-miss", http.StatusOK, false),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			shared, private := mapStorage{}, mapStorage{}
+			opts := Options{
+				Parent: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Header: http.Header{
+								"Etag":          []string{`"tag1"`},
+								"Cache-Control": []string{test.CacheControl},
+							},
+							Body: io.NopCloser(strings.NewReader("content")),
+						}, nil
+					},
+				},
+				Speculations: []Speculation{},
+			}
+			if test.Shared {
+				opts.Storage = shared
+			}
+			if test.Private {
+				opts.PrivateStorage = private
+			}
+			tr := New(opts)
+
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			resp.Body.Close()
+			if got, want := resp.Header.Get("Cache-Status"), withOperation(test.Expected); got != want {
+				t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, want)
+			}
+			if got := len(shared) > 0; got != test.InShared {
+				t.Errorf("stored in shared Storage = %v, want %v", got, test.InShared)
+			}
+			if got := len(private) > 0; got != test.InPrivate {
+				t.Errorf("stored in PrivateStorage = %v, want %v", got, test.InPrivate)
+			}
+		})
+	}
+}
+
+func TestTieredStorage(t *testing.T) {
+	ctx := context.Background()
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	shared, private := mapStorage{}, mapStorage{}
+	storage := &tieredStorage{private: private, shared: shared}
+
+	put := func(etag, cacheControl string) {
+		t.Helper()
+		resp := &http.Response{
+			Header:  http.Header{"Etag": []string{etag}, "Cache-Control": []string{cacheControl}},
+			Request: req,
+		}
+		if err := storage.Put(ctx, resp); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	get := func() string {
+		t.Helper()
+		resp, err := storage.Get(ctx, req)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if resp == nil {
+			return ""
+		}
+		resp.Body.Close()
+		return resp.Header.Get("Etag")
+	}
+
+	put(`"shared"`, "public, max-age=60")
+	if got := get(); got != `"shared"` {
+		t.Errorf("Get() Etag = %q, want %q", got, `"shared"`)
+	}
+	put(`"private"`, "private, max-age=60")
+	if got := get(); got != `"private"` {
+		t.Errorf("Get() Etag = %q, want the PrivateStorage response %q", got, `"private"`)
+	}
+	put(`"public"`, "public, max-age=60")
+	if got := get(); got != `"public"` {
+		t.Errorf("Get() Etag = %q, want the outdated private response to be deleted, %q", got, `"public"`)
+	}
+	if err := storage.Delete(ctx, req); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := get(); got != "" {
+		t.Errorf("Get() after Delete() Etag = %q, want a miss", got)
+	}
+}
@@ -0,0 +1,127 @@
+package ghtransport
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is the GitHub REST API rate limit state of a principal for a resource, as reported by the
+// "X-Ratelimit-*" headers of the most recent upstream response.
+type RateLimit struct {
+	// Principal is the hashed token (see HashToken) of the principal, the hash of an empty token if the
+	// requests were unauthenticated.
+	Principal string
+	// Resource is the rate limit resource (ex: "core", "search", "graphql").
+	Resource string
+	// Limit is the maximum number of requests per window.
+	Limit int
+	// Remaining is the number of requests remaining in the current window.
+	Remaining int
+	// Used is the number of requests made in the current window.
+	Used int
+	// Reset is when the current window resets.
+	Reset time.Time
+	// Updated is when the state was last observed.
+	Updated time.Time
+}
+
+// rateLimitKey identifies a RateLimit.
+type rateLimitKey struct {
+	principal string
+	resource  string
+}
+
+// rateLimits tracks the RateLimit of each principal and resource seen by a Transport.
+type rateLimits struct {
+	mu     sync.Mutex
+	limits map[rateLimitKey]RateLimit
+}
+
+// parseRateLimit parses the "X-Ratelimit-*" headers of the response, ok is false if they are missing.
+func parseRateLimit(headers http.Header) (_ RateLimit, ok bool) {
+	limit, err := strconv.Atoi(headers.Get("X-Ratelimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	remaining, err := strconv.Atoi(headers.Get("X-Ratelimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	reset, err := strconv.ParseInt(headers.Get("X-Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return RateLimit{}, false
+	}
+	used, err := strconv.Atoi(headers.Get("X-Ratelimit-Used"))
+	if err != nil {
+		used = limit - remaining // Not sent by older GitHub Enterprise Server versions
+	}
+	resource := headers.Get("X-Ratelimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+	return RateLimit{
+		Resource:  resource,
+		Limit:     limit,
+		Remaining: remaining,
+		Used:      used,
+		Reset:     time.Unix(reset, 0).UTC(),
+	}, true
+}
+
+// noteRateLimit records the rate limit state reported by the upstream response to the request, calling
+// Options.OnRateLimit if it changed. As concurrent responses may arrive out of order, a response reporting
+// more remaining requests in the same window as the recorded state is ignored.
+func (t *Transport) noteRateLimit(req *http.Request, resp *http.Response) {
+	limit, ok := parseRateLimit(resp.Header)
+	if !ok {
+		return
+	}
+	limit.Principal = HashToken(req.Header.Get("Authorization"))
+	limit.Updated = t.now()
+	key := rateLimitKey{principal: limit.Principal, resource: limit.Resource}
+
+	t.rateLimits.mu.Lock()
+	prev, seen := t.rateLimits.limits[key]
+	if seen && limit.Reset.Equal(prev.Reset) && limit.Remaining > prev.Remaining {
+		t.rateLimits.mu.Unlock()
+		return
+	}
+	if t.rateLimits.limits == nil {
+		t.rateLimits.limits = make(map[rateLimitKey]RateLimit)
+	}
+	t.rateLimits.limits[key] = limit
+	t.rateLimits.mu.Unlock()
+
+	changed := !seen || limit.Remaining != prev.Remaining || limit.Limit != prev.Limit || !limit.Reset.Equal(prev.Reset)
+	if changed && t.opts.OnRateLimit != nil {
+		t.opts.OnRateLimit(limit)
+	}
+}
+
+// RateLimit returns the most recently observed rate limit state of the principal (identified by its
+// "Authorization" header) for the resource (ex: "core"), ok is false if none was observed yet.
+func (t *Transport) RateLimit(authorization, resource string) (_ RateLimit, ok bool) {
+	t.rateLimits.mu.Lock()
+	defer t.rateLimits.mu.Unlock()
+	limit, ok := t.rateLimits.limits[rateLimitKey{principal: HashToken(authorization), resource: resource}]
+	return limit, ok
+}
+
+// RateLimits returns a snapshot of the most recently observed rate limit state of every principal and
+// resource, sorted by principal then resource.
+func (t *Transport) RateLimits() []RateLimit {
+	t.rateLimits.mu.Lock()
+	limits := make([]RateLimit, 0, len(t.rateLimits.limits))
+	for _, limit := range t.rateLimits.limits {
+		limits = append(limits, limit)
+	}
+	t.rateLimits.mu.Unlock()
+	slices.SortFunc(limits, func(a, b RateLimit) int {
+		return cmp.Or(cmp.Compare(a.Principal, b.Principal), cmp.Compare(a.Resource, b.Resource))
+	})
+	return limits
+}
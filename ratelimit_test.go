@@ -0,0 +1,172 @@
+package ghtransport
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	reset := time.Date(2025, time.February, 1, 13, 0, 0, 0, time.UTC)
+	tests := []struct {
+		Name     string
+		Header   http.Header
+		Expected RateLimit
+		OK       bool
+	}{
+		{
+			Name: "core",
+			Header: http.Header{
+				"X-Ratelimit-Limit":     []string{"5000"},
+				"X-Ratelimit-Remaining": []string{"4990"},
+				"X-Ratelimit-Used":      []string{"10"},
+				"X-Ratelimit-Reset":     []string{strconv.FormatInt(reset.Unix(), 10)},
+				"X-Ratelimit-Resource":  []string{"core"},
+			},
+			Expected: RateLimit{Resource: "core", Limit: 5000, Remaining: 4990, Used: 10, Reset: reset},
+			OK:       true,
+		},
+		{
+			Name: "search",
+			Header: http.Header{
+				"X-Ratelimit-Limit":     []string{"30"},
+				"X-Ratelimit-Remaining": []string{"29"},
+				"X-Ratelimit-Used":      []string{"1"},
+				"X-Ratelimit-Reset":     []string{strconv.FormatInt(reset.Unix(), 10)},
+				"X-Ratelimit-Resource":  []string{"search"},
+			},
+			Expected: RateLimit{Resource: "search", Limit: 30, Remaining: 29, Used: 1, Reset: reset},
+			OK:       true,
+		},
+		{
+			Name: "no used or resource",
+			Header: http.Header{
+				"X-Ratelimit-Limit":     []string{"5000"},
+				"X-Ratelimit-Remaining": []string{"4990"},
+				"X-Ratelimit-Reset":     []string{strconv.FormatInt(reset.Unix(), 10)},
+			},
+			Expected: RateLimit{Resource: "core", Limit: 5000, Remaining: 4990, Used: 10, Reset: reset},
+			OK:       true,
+		},
+		{
+			Name:   "missing",
+			Header: http.Header{},
+		},
+		{
+			Name: "invalid",
+			Header: http.Header{
+				"X-Ratelimit-Limit":     []string{"5000"},
+				"X-Ratelimit-Remaining": []string{"many"},
+				"X-Ratelimit-Reset":     []string{strconv.FormatInt(reset.Unix(), 10)},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			got, ok := parseRateLimit(test.Header)
+			if ok != test.OK {
+				t.Fatalf("parseRateLimit() ok = %v, want %v", ok, test.OK)
+			}
+			if !reflect.DeepEqual(got, test.Expected) {
+				t.Errorf("parseRateLimit() = %+v, want %+v", got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestTransport_RateLimits(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	reset := now.Add(time.Hour)
+	var remaining, resetAt int
+	var changes []RateLimit
+	tr := New(Options{
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				resource := "core"
+				if strings.HasPrefix(req.URL.Path, "/search/") {
+					resource = "search"
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header: http.Header{
+						"X-Ratelimit-Limit":     []string{"5000"},
+						"X-Ratelimit-Remaining": []string{strconv.Itoa(remaining)},
+						"X-Ratelimit-Used":      []string{strconv.Itoa(5000 - remaining)},
+						"X-Ratelimit-Reset":     []string{strconv.FormatInt(reset.Add(time.Duration(resetAt)*time.Hour).Unix(), 10)},
+						"X-Ratelimit-Resource":  []string{resource},
+					},
+					Body: io.NopCloser(strings.NewReader("content")),
+				}, nil
+			},
+		},
+		OnRateLimit: func(limit RateLimit) {
+			changes = append(changes, limit)
+		},
+	})
+	tr.now = func() time.Time { return now }
+
+	roundTrip := func(path, authorization string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "https://api.github.com"+path, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", authorization)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	remaining = 4990
+	roundTrip("/repos/foo/bar", "Bearer alice")
+	remaining = 4980
+	roundTrip("/repos/foo/bar", "Bearer bob")
+	remaining = 20
+	roundTrip("/search/issues", "Bearer alice")
+	// The /rate_limit bypass is still observed
+	remaining = 4985
+	roundTrip("/rate_limit", "Bearer alice")
+	// An out of order response from earlier in the same window is ignored
+	remaining = 4995
+	roundTrip("/repos/foo/bar", "Bearer alice")
+	// Unchanged, no callback
+	remaining = 4985
+	roundTrip("/repos/foo/bar", "Bearer alice")
+
+	alice, ok := tr.RateLimit("Bearer alice", "core")
+	if !ok {
+		t.Fatal("RateLimit() ok = false, want true")
+	}
+	if want := (RateLimit{Principal: HashToken("Bearer alice"), Resource: "core", Limit: 5000, Remaining: 4985, Used: 15, Reset: reset, Updated: now}); alice != want {
+		t.Errorf("RateLimit() = %+v, want %+v", alice, want)
+	}
+	if _, ok := tr.RateLimit("Bearer bob", "search"); ok {
+		t.Error("RateLimit() of an unobserved resource ok = true, want false")
+	}
+	if got := len(changes); got != 4 {
+		t.Errorf("OnRateLimit() called %d times, want 4", got)
+	}
+
+	// A new window replaces the state, even with more remaining requests
+	resetAt, remaining = 1, 4999
+	roundTrip("/repos/foo/bar", "Bearer alice")
+	if alice, _ := tr.RateLimit("Bearer alice", "core"); alice.Remaining != 4999 || !alice.Reset.Equal(reset.Add(time.Hour)) {
+		t.Errorf("RateLimit() after reset = %+v, want the new window", alice)
+	}
+
+	limits := tr.RateLimits()
+	if len(limits) != 3 {
+		t.Fatalf("RateLimits() = %+v, want 3 entries", limits)
+	}
+	for idx := 1; idx < len(limits); idx++ {
+		if prev, cur := limits[idx-1], limits[idx]; prev.Principal > cur.Principal || (prev.Principal == cur.Principal && prev.Resource >= cur.Resource) {
+			t.Errorf("RateLimits() is not sorted: %+v", limits)
+		}
+	}
+}
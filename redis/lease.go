@@ -0,0 +1,80 @@
+package redisstorage
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultLeaseTTL is the default expiration of a revalidation lease, it bounds how long other processes
+// wait if the holder of a lease crashes before releasing it.
+const DefaultLeaseTTL = 30 * time.Second
+
+// DefaultLeasePollInterval is the default interval at which Wait checks if a lease has been released.
+const DefaultLeasePollInterval = 50 * time.Millisecond
+
+// releaseScript deletes the lease key only if it is still held by the caller (it may have expired and
+// been acquired by someone else in the meantime).
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// leaseKey generates the Redis key of the revalidation lease for the request.
+func (s *Storage) leaseKey(req *http.Request) string {
+	return s.key(req) + ":lease"
+}
+
+// Lease implements the ghtransport.Leaser interface via SET NX, such that only one process sharing the
+// Redis instance revalidates a given URL at a time.
+func (s *Storage) Lease(ctx context.Context, req *http.Request) (func(context.Context) error, error) {
+	ttl := s.LeaseTTL
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	key, token := s.leaseKey(req), rand.Text()
+	acquired, err := s.Client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("(*redis.Client).SetNX failed: %w", err)
+	}
+	if !acquired {
+		return nil, nil
+	}
+	return func(ctx context.Context) error {
+		if err := releaseScript.Run(ctx, s.Client, []string{key}, token).Err(); err != nil {
+			return fmt.Errorf("(*redis.Script).Run failed: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// Wait implements the ghtransport.Leaser interface by polling until the lease key no longer exists.
+func (s *Storage) Wait(ctx context.Context, req *http.Request) error {
+	interval := s.LeasePollInterval
+	if interval <= 0 {
+		interval = DefaultLeasePollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	key := s.leaseKey(req)
+	for {
+		n, err := s.Client.Exists(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("(*redis.Client).Exists failed: %w", err)
+		}
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
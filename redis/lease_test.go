@@ -0,0 +1,73 @@
+package redisstorage
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestStorage_Lease(t *testing.T) {
+	if os.Getenv("REDIS_URL") == "" {
+		t.Skip("REDIS_URL is not set, skipping test")
+	}
+
+	storage := New(redis.NewClient(&redis.Options{
+		Addr: os.Getenv("REDIS_URL"),
+	}))
+
+	// Override the Key function for the test to use a random prefix
+	prefix := strconv.Itoa(rand.Int())
+	storage.Key = func(req *http.Request) string {
+		return prefix + "/" + strings.TrimPrefix(req.URL.String(), "https://")
+	}
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    testURL,
+	}
+
+	// Ensure the first caller acquires the lease
+	release, err := storage.Lease(t.Context(), req)
+	if err != nil {
+		t.Fatalf("(*Storage).Lease failed: %v", err)
+	} else if release == nil {
+		t.Fatal("(*Storage).Lease did not acquire the unheld lease")
+	}
+
+	// Ensure the second caller does not
+	if other, err := storage.Lease(t.Context(), req); err != nil {
+		t.Fatalf("(*Storage).Lease failed: %v", err)
+	} else if other != nil {
+		t.Fatal("(*Storage).Lease acquired an already held lease")
+	}
+
+	// Ensure Wait blocks while the lease is held
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	if err := storage.Wait(ctx, req); err != context.DeadlineExceeded {
+		t.Fatalf("(*Storage).Wait returned %v while the lease was held, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Ensure Wait returns once the lease is released
+	if err := release(t.Context()); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if err := storage.Wait(t.Context(), req); err != nil {
+		t.Fatalf("(*Storage).Wait failed: %v", err)
+	}
+
+	// Ensure the lease can be acquired again
+	if release, err := storage.Lease(t.Context(), req); err != nil {
+		t.Fatalf("(*Storage).Lease failed: %v", err)
+	} else if release == nil {
+		t.Fatal("(*Storage).Lease did not acquire the released lease")
+	} else if err := release(t.Context()); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+}
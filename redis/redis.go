@@ -13,7 +13,7 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-// Key generates the Redis key from the URL.
+// Key generates the default Redis key from the URL, it may be overridden per Storage via (*Storage).Key.
 var Key = func(req *http.Request) string {
 	return strings.TrimPrefix(req.URL.String(), "https://")
 }
@@ -22,10 +22,24 @@ var Key = func(req *http.Request) string {
 type Storage struct {
 	Client     *redis.Client
 	Expiration time.Duration
+	// Key generates the Redis key from the URL, defaults to the package-level Key if nil.
+	Key func(*http.Request) string
+	// LeaseTTL is the expiration of a revalidation lease (see Lease), defaults to DefaultLeaseTTL.
+	LeaseTTL time.Duration
+	// LeasePollInterval is how often Wait checks if a lease was released, defaults to DefaultLeasePollInterval.
+	LeasePollInterval time.Duration
+}
+
+// key generates the Redis key for the request using (*Storage).Key, falling back to the package-level Key.
+func (s *Storage) key(req *http.Request) string {
+	if s.Key != nil {
+		return s.Key(req)
+	}
+	return Key(req)
 }
 
 func (s *Storage) Get(ctx context.Context, req *http.Request) (*http.Response, error) {
-	value, err := s.Client.Get(ctx, Key(req)).Result()
+	value, err := s.Client.Get(ctx, s.key(req)).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, nil
@@ -44,12 +58,19 @@ func (s *Storage) Put(ctx context.Context, resp *http.Response) error {
 	if err != nil {
 		return fmt.Errorf("httputil.DumpResponse failed: %w", err)
 	}
-	if err := s.Client.Set(ctx, Key(resp.Request), value, s.Expiration).Err(); err != nil {
+	if err := s.Client.Set(ctx, s.key(resp.Request), value, s.Expiration).Err(); err != nil {
 		return fmt.Errorf("(*redis.Client).Set failed: %w", err)
 	}
 	return nil
 }
 
+func (s *Storage) Delete(ctx context.Context, req *http.Request) error {
+	if err := s.Client.Del(ctx, s.key(req)).Err(); err != nil {
+		return fmt.Errorf("(*redis.Client).Del failed: %w", err)
+	}
+	return nil
+}
+
 func New(client *redis.Client) *Storage {
 	return &Storage{Client: client}
 }
@@ -0,0 +1,155 @@
+package ghtransport
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Route is the caching policy for the GitHub REST API requests matching a path template. A policy table is
+// loaded from JSON with ParseRoutes or LoadRoutes. YAML is not supported by this module (it has no external
+// dependencies), but the struct tags (and Duration) allow a YAML policy table to be decoded with any YAML
+// library that honors them (ex: "gopkg.in/yaml.v3").
+type Route struct {
+	// Path is the REST path template (ex: "/repos/{owner}/{repo}/git/blobs/{sha}"), see Speculation.Pattern
+	// for the syntax. An empty Path matches every request.
+	Path string `json:"path" yaml:"path"`
+	// Bypass forwards matching requests upstream without reading from or writing to the Storage.
+	Bypass bool `json:"bypass,omitempty" yaml:"bypass,omitempty"`
+	// TTL, if positive, is how long a stored response is fresh: it is served without revalidation while
+	// younger than the TTL. It takes precedence over Options.MaxAge and Options.TTL.
+	TTL Duration `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// Speculations, if non-nil, replaces Options.Speculations for matching requests.
+	Speculations []Speculation `json:"speculations,omitempty" yaml:"speculations,omitempty"`
+	// DropHeaders are the response headers that are removed before storing a response.
+	DropHeaders []string `json:"drop_headers,omitempty" yaml:"drop_headers,omitempty"`
+	// Retention, if positive, is the maximum age of a stored response that may be used at all, an older
+	// stored response is treated as if nothing was stored.
+	Retention Duration `json:"retention,omitempty" yaml:"retention,omitempty"`
+	// Immutable lists the path (or query) parameters that identify content-addressed, immutable responses.
+	// If every one of them is a full commit/object SHA, a stored response is served without revalidation.
+	Immutable []string `json:"immutable,omitempty" yaml:"immutable,omitempty"`
+}
+
+// Routes is the default policy table. It may be overridden per Transport via Options.Routes.
+var Routes = []Route{
+	{
+		// The rate limit status is never useful stale, and requesting it does not count against the rate limit
+		Path:   "/rate_limit",
+		Bypass: true,
+	},
+	{
+		Path:      "/repos/{owner}/{repo}/git/blobs/{file_sha}",
+		Immutable: []string{"file_sha"},
+	},
+	{
+		Path:      "/repos/{owner}/{repo}/git/trees/{tree_sha}",
+		Immutable: []string{"tree_sha"},
+	},
+	{
+		Path:      "/repos/{owner}/{repo}/git/commits/{commit_sha}",
+		Immutable: []string{"commit_sha"},
+	},
+	{
+		Path:      "/repos/{owner}/{repo}/commits/{ref}",
+		Immutable: []string{"ref"},
+	},
+	{
+		// Only when the "ref" query parameter is a full SHA, otherwise it's the (mutable) default branch
+		Path:      "/repos/{owner}/{repo}/contents/{path...}",
+		Immutable: []string{"ref"},
+	},
+}
+
+// Duration is a time.Duration that is (un)marshaled as text, such as "30s" or "1h".
+type Duration time.Duration
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ParseRoutes parses a JSON policy table, an array of Route objects. YAML is not supported, see Route.
+func ParseRoutes(data []byte) ([]Route, error) {
+	var routes []Route
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal failed: %w", err)
+	}
+	return routes, nil
+}
+
+// LoadRoutes reads and parses a JSON policy table from a file, see ParseRoutes. A file with a ".yaml" or
+// ".yml" extension is rejected, as YAML is not supported (see Route).
+func LoadRoutes(name string) ([]Route, error) {
+	if ext := strings.ToLower(filepath.Ext(name)); ext == ".yaml" || ext == ".yml" {
+		return nil, fmt.Errorf("LoadRoutes only supports JSON, decode %s with a YAML library instead", name)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile failed: %w", err)
+	}
+	return ParseRoutes(data)
+}
+
+// route returns the policy for the request: the first Route whose Path matches, or the zero Route.
+func (t *Transport) route(req *http.Request) Route {
+	var path string
+	if req.URL != nil {
+		path = req.URL.Path
+	}
+	for _, route := range t.opts.Routes {
+		if route.Path == "" || matchPattern(route.Path, path) {
+			return route
+		}
+	}
+	return Route{}
+}
+
+// immutable reports if the request is for a content-addressed, immutable response, see Route.Immutable.
+func (r Route) immutable(req *http.Request) bool {
+	if len(r.Immutable) == 0 || req.URL == nil {
+		return false
+	}
+	params, ok := matchParams(r.Path, req.URL.Path)
+	if !ok {
+		return false
+	}
+	for _, name := range r.Immutable {
+		val, ok := params[name]
+		if !ok {
+			val = req.URL.Query().Get(name)
+		}
+		if !fullSHA(val) {
+			return false
+		}
+	}
+	return true
+}
+
+// fullSHA reports if the value is a full (SHA-1 or SHA-256) hex object ID, as opposed to an abbreviated
+// SHA or a (mutable) branch or tag name.
+func fullSHA(val string) bool {
+	if len(val) != 40 && len(val) != 64 {
+		return false
+	}
+	for _, c := range val {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return true
+}
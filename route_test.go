@@ -0,0 +1,339 @@
+package ghtransport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes([]byte(`[
+		{"path": "/rate_limit", "bypass": true},
+		{"path": "/repos/{owner}/{repo}/git/blobs/{sha}", "ttl": "24h", "retention": "720h", "drop_headers": ["X-Oauth-Scopes"]},
+		{"path": "/search/{kind}", "speculations": [{"name": "empty-search", "body": "{\"total_count\":0}"}]}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseRoutes() error = %v", err)
+	}
+	if len(routes) != 3 {
+		t.Fatalf("ParseRoutes() returned %d routes, want 3", len(routes))
+	}
+	if !routes[0].Bypass {
+		t.Errorf("routes[0].Bypass = false, want true")
+	}
+	if got := time.Duration(routes[1].TTL); got != 24*time.Hour {
+		t.Errorf("routes[1].TTL = %v, want %v", got, 24*time.Hour)
+	}
+	if got := time.Duration(routes[1].Retention); got != 720*time.Hour {
+		t.Errorf("routes[1].Retention = %v, want %v", got, 720*time.Hour)
+	}
+	if len(routes[1].DropHeaders) != 1 || routes[1].DropHeaders[0] != "X-Oauth-Scopes" {
+		t.Errorf("routes[1].DropHeaders = %v, want [X-Oauth-Scopes]", routes[1].DropHeaders)
+	}
+	if len(routes[2].Speculations) != 1 || routes[2].Speculations[0].Body != `{"total_count":0}` {
+		t.Errorf("routes[2].Speculations = %v, want a single empty-search speculation", routes[2].Speculations)
+	}
+
+	if _, err := ParseRoutes([]byte(`[{"path": "/", "ttl": "forever"}]`)); err == nil {
+		t.Errorf("ParseRoutes() with an invalid TTL succeeded, want error")
+	}
+}
+
+func TestLoadRoutes(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"routes.json": `[{"path": "/rate_limit", "bypass": true}]`,
+		"routes.yaml": "- path: /rate_limit\n  bypass: true\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("os.WriteFile failed: %v", err)
+		}
+	}
+
+	routes, err := LoadRoutes(filepath.Join(dir, "routes.json"))
+	if err != nil {
+		t.Fatalf("LoadRoutes() error = %v", err)
+	}
+	if len(routes) != 1 || !routes[0].Bypass {
+		t.Errorf("LoadRoutes() = %+v, want a single bypass route", routes)
+	}
+	if _, err := LoadRoutes(filepath.Join(dir, "routes.yaml")); err == nil || !strings.Contains(err.Error(), "only supports JSON") {
+		t.Errorf("LoadRoutes() of a YAML file error = %v, want an unsupported error", err)
+	}
+}
+
+func TestTransport_route(t *testing.T) {
+	tr := New(Options{
+		Routes: []Route{
+			{Path: "/repos/{owner}/{repo}/git/blobs/{sha}", TTL: Duration(time.Hour)},
+			{Path: "/repos/{owner}/{repo}/git/{rest...}", Bypass: true},
+			{Path: "", Retention: Duration(time.Minute)},
+		},
+	})
+	tests := map[string]struct {
+		Path     string
+		Expected Route
+	}{
+		"first":      {Path: "/repos/foo/bar/git/blobs/deadbeef", Expected: tr.opts.Routes[0]},
+		"enterprise": {Path: "/api/v3/repos/foo/bar/git/blobs/deadbeef", Expected: tr.opts.Routes[0]},
+		"second":     {Path: "/repos/foo/bar/git/trees/deadbeef", Expected: tr.opts.Routes[1]},
+		"fallback":   {Path: "/users/foo", Expected: tr.opts.Routes[2]},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com"+test.Path, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if got := tr.route(req); got.Path != test.Expected.Path {
+				t.Errorf("route(%q) = %q, want %q", test.Path, got.Path, test.Expected.Path)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_Route(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		Route           Route
+		CachedDate      time.Time
+		WantUpstream    bool
+		WantBody        string
+		WantCacheStatus string
+	}{
+		"fresh": {
+			Route:           Route{TTL: Duration(time.Hour)},
+			CachedDate:      now.Add(-10 * time.Minute),
+			WantUpstream:    false,
+			WantBody:        "cached content",
+			WantCacheStatus: cacheStatusHit(CacheName) + "; ttl=3000",
+		},
+		"expired": {
+			Route:           Route{TTL: Duration(time.Minute)},
+			CachedDate:      now.Add(-10 * time.Minute),
+			WantUpstream:    true,
+			WantBody:        "cached content",
+			WantCacheStatus: cacheStatusHit(CacheName),
+		},
+		"retained": {
+			Route:           Route{Retention: Duration(time.Hour)},
+			CachedDate:      now.Add(-10 * time.Minute),
+			WantUpstream:    true,
+			WantBody:        "cached content",
+			WantCacheStatus: cacheStatusHit(CacheName),
+		},
+		"retention_exceeded": {
+			Route:           Route{Retention: Duration(time.Minute)},
+			CachedDate:      now.Add(-10 * time.Minute),
+			WantUpstream:    true,
+			WantBody:        "live content",
+			WantCacheStatus: cacheStatusForward(CacheName, "uri-miss", http.StatusOK, false),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstream := false
+			tr := New(Options{
+				Storage: &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						return &http.Response{
+							StatusCode:    http.StatusOK,
+							Status:        "200 OK",
+							Header:        http.Header{"Etag": []string{`"tag1"`}, "Date": []string{test.CachedDate.Format(http.TimeFormat)}},
+							Body:          io.NopCloser(strings.NewReader("cached content")),
+							ContentLength: 14,
+						}, nil
+					},
+				},
+				Parent: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						upstream = true
+						if strings.HasPrefix(req.Header.Get("If-None-Match"), `"tag1"`) {
+							return &http.Response{
+								StatusCode: http.StatusNotModified,
+								Header:     http.Header{},
+								Body:       io.NopCloser(strings.NewReader("")),
+							}, nil
+						}
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Header:     http.Header{},
+							Body:       io.NopCloser(strings.NewReader("live content")),
+						}, nil
+					},
+				},
+				Routes: []Route{test.Route},
+			})
+			tr.now = func() time.Time { return now }
+
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			defer resp.Body.Close()
+			if upstream != test.WantUpstream {
+				t.Errorf("RoundTrip() upstream = %v, want %v", upstream, test.WantUpstream)
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != test.WantBody {
+				t.Errorf("RoundTrip() body = %q, want %q", body, test.WantBody)
+			}
+			if got, want := resp.Header.Get("Cache-Status"), withOperation(test.WantCacheStatus); got != want {
+				t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_RouteDropHeaders(t *testing.T) {
+	var stored *http.Response
+	tr := New(Options{
+		Storage: &mockStorage{
+			putFunc: func(ctx context.Context, resp *http.Response) error {
+				stored = resp
+				return nil
+			},
+		},
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header: http.Header{
+						"Etag":           []string{`"live"`},
+						"X-Oauth-Scopes": []string{"repo"},
+					},
+					Body: io.NopCloser(strings.NewReader("live content")),
+				}, nil
+			},
+		},
+		Routes: []Route{{DropHeaders: []string{"X-Oauth-Scopes"}}},
+	})
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	resp.Body.Close()
+	if stored == nil {
+		t.Fatal("RoundTrip() did not store the response")
+	}
+	if _, ok := stored.Header["X-Oauth-Scopes"]; ok {
+		t.Errorf("stored response kept the dropped X-Oauth-Scopes header")
+	}
+	if resp.Header.Get("X-Oauth-Scopes") != "repo" {
+		t.Errorf("RoundTrip() response lost the X-Oauth-Scopes header")
+	}
+}
+
+func TestRoute_immutable(t *testing.T) {
+	const sha = "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+	tests := map[string]struct {
+		URL      string
+		Expected bool
+	}{
+		"blob":           {URL: "/repos/foo/bar/git/blobs/" + sha, Expected: true},
+		"tree":           {URL: "/repos/foo/bar/git/trees/" + sha + "?recursive=1", Expected: true},
+		"git_commit":     {URL: "/repos/foo/bar/git/commits/" + sha, Expected: true},
+		"commit":         {URL: "/repos/foo/bar/commits/" + sha, Expected: true},
+		"commit_sha256":  {URL: "/repos/foo/bar/commits/" + sha + "0123456789abcdef01234567", Expected: true},
+		"commit_branch":  {URL: "/repos/foo/bar/commits/main", Expected: false},
+		"commit_short":   {URL: "/repos/foo/bar/commits/" + sha[:7], Expected: false},
+		"contents_ref":   {URL: "/repos/foo/bar/contents/a/b.go?ref=" + sha, Expected: true},
+		"contents":       {URL: "/repos/foo/bar/contents/a/b.go", Expected: false},
+		"contents_named": {URL: "/repos/foo/bar/contents/a/b.go?ref=main", Expected: false},
+		"enterprise":     {URL: "/api/v3/repos/foo/bar/git/blobs/" + sha, Expected: true},
+		"mutable":        {URL: "/repos/foo/bar", Expected: false},
+	}
+	tr := NewTransport(nil, nil)
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com"+test.URL, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if got := tr.route(req).immutable(req); got != test.Expected {
+				t.Errorf("immutable(%q) = %v, want %v", test.URL, got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_Immutable(t *testing.T) {
+	const sha = "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+	tests := map[string]struct {
+		Authorization   string
+		WantUpstream    bool
+		WantCacheStatus string
+	}{
+		"hit": {
+			Authorization:   "Bearer hunter2",
+			WantUpstream:    false,
+			WantCacheStatus: cacheStatusOperation(cacheStatusDetail(cacheStatusHit(CacheName), "immutable"), "git/get-blob"),
+		},
+		"other_token": {
+			// A different token must still be authorized by GitHub
+			Authorization:   "Bearer hunter3",
+			WantUpstream:    true,
+			WantCacheStatus: cacheStatusOperation(cacheStatusHit(CacheName), "git/get-blob"),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstream := false
+			tr := NewTransport(&mockStorage{
+				getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Status:     "200 OK",
+						Header: http.Header{
+							"Etag":                       []string{`"tag1"`},
+							"Vary":                       []string{"Authorization"},
+							VaryPrefix + "Authorization": []string{HashToken("Bearer hunter2")},
+						},
+						Body:          io.NopCloser(strings.NewReader("blob content")),
+						ContentLength: 12,
+					}, nil
+				},
+			}, &mockRoundTripper{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					upstream = true
+					return &http.Response{
+						StatusCode: http.StatusNotModified,
+						Header:     http.Header{},
+						Body:       io.NopCloser(strings.NewReader("")),
+					}, nil
+				},
+			})
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar/git/blobs/"+sha, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			req.Header.Set("Authorization", test.Authorization)
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			defer resp.Body.Close()
+			if upstream != test.WantUpstream {
+				t.Errorf("RoundTrip() upstream = %v, want %v", upstream, test.WantUpstream)
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != "blob content" {
+				t.Errorf("RoundTrip() body = %q, want %q", body, "blob content")
+			}
+			if got := resp.Header.Get("Cache-Status"); got != test.WantCacheStatus {
+				t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, test.WantCacheStatus)
+			}
+		})
+	}
+}
@@ -20,7 +20,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
-// Key generates the S3 key from the URL.
+// Key generates the default S3 key from the URL, it may be overridden per Storage via (*Storage).Key.
 var Key = func(req *http.Request) string {
 	return strings.TrimPrefix(req.URL.String(), "https://")
 }
@@ -48,12 +48,22 @@ type Storage struct {
 	Client *s3.Client
 	Bucket string
 	Prefix string
+	// Key generates the S3 key (before Prefix is applied) from the URL, defaults to the package-level Key if nil.
+	Key func(*http.Request) string
+}
+
+// key generates the S3 key for the request using (*Storage).Key, falling back to the package-level Key.
+func (s *Storage) key(req *http.Request) string {
+	if s.Key != nil {
+		return s.Key(req)
+	}
+	return Key(req)
 }
 
 func (s *Storage) Get(ctx context.Context, req *http.Request) (*http.Response, error) {
 	out, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(s.Bucket),
-		Key:    aws.String(path.Join(s.Prefix, Key(req))),
+		Key:    aws.String(path.Join(s.Prefix, s.key(req))),
 	})
 	if err != nil {
 		var nsk *types.NoSuchKey
@@ -109,7 +119,7 @@ func (s *Storage) Put(ctx context.Context, resp *http.Response) error {
 
 	input := &s3.PutObjectInput{
 		Bucket:        aws.String(s.Bucket),
-		Key:           aws.String(path.Join(s.Prefix, Key(resp.Request))),
+		Key:           aws.String(path.Join(s.Prefix, s.key(resp.Request))),
 		Body:          &buf,
 		ContentLength: aws.Int64(int64(buf.Len())),
 		ContentMD5:    aws.String(base64.StdEncoding.EncodeToString(checksum[:])),
@@ -142,6 +152,16 @@ func (s *Storage) Put(ctx context.Context, resp *http.Response) error {
 	return nil
 }
 
+func (s *Storage) Delete(ctx context.Context, req *http.Request) error {
+	if _, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(path.Join(s.Prefix, s.key(req))),
+	}); err != nil {
+		return fmt.Errorf("(*s3.Client).DeleteObject failed: %w", err)
+	}
+	return nil
+}
+
 // New returns a new Storage for the given bucket and (optional) prefix.
 func New(client *s3.Client, bucket string, prefix ...string) (*Storage, error) {
 	if client == nil {
@@ -0,0 +1,102 @@
+package ghtransport
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Speculation is a response body that is speculatively guessed for requests with no (matching) cached
+// response, such that endpoints returning an "empty" result can still benefit from a 304 Not Modified.
+type Speculation struct {
+	// Name identifies the guess in the "Cache-Status" header (as "detail=speculative-<Name>").
+	Name string `json:"name" yaml:"name"`
+	// Pattern is the URL path template the guess applies to (ex: "/search/{kind}"). Each "{name}" matches a
+	// single path segment and a trailing "{name...}" matches the remainder of the path. An empty Pattern
+	// matches every path. The "/api/v3" prefix of GitHub Enterprise Server is ignored.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Body is the guessed response body.
+	Body string `json:"body" yaml:"body"`
+}
+
+// Speculations are the default speculative response bodies. It may be overridden per Transport via
+// Options.Speculations.
+var Speculations = []Speculation{
+	{
+		Name:    "empty-search",
+		Pattern: "/search/{kind}",
+		Body:    `{"total_count":0,"incomplete_results":false,"items":[]}`,
+	},
+	{
+		Name:    "empty-workflow-runs",
+		Pattern: "/repos/{owner}/{repo}/actions/runs",
+		Body:    `{"total_count":0,"workflow_runs":[]}`,
+	},
+	{
+		Name:    "empty-workflow-runs",
+		Pattern: "/repos/{owner}/{repo}/actions/workflows/{workflow_id}/runs",
+		Body:    `{"total_count":0,"workflow_runs":[]}`,
+	},
+	{
+		Name: "empty-array",
+		Body: `[]`,
+	},
+}
+
+// speculations returns the speculative response bodies that apply to the request, per its Route if set.
+func (t *Transport) speculations(req *http.Request) []*Speculation {
+	var path string
+	if req.URL != nil {
+		path = req.URL.Path
+	}
+	speculations := t.opts.Speculations
+	if route := t.route(req); route.Speculations != nil {
+		speculations = route.Speculations
+	}
+	var matched []*Speculation
+	for idx := range speculations {
+		if spec := &speculations[idx]; spec.Pattern == "" || matchPattern(spec.Pattern, path) {
+			matched = append(matched, spec)
+		}
+	}
+	return matched
+}
+
+// matchPattern reports if the URL path matches the path template. See Speculation.Pattern for the syntax.
+func matchPattern(pattern, path string) bool {
+	_, ok := matchParams(pattern, path)
+	return ok
+}
+
+// matchParams matches the URL path against the path template, returning the values of its parameters.
+func matchParams(pattern, path string) (map[string]string, bool) {
+	path = strings.TrimPrefix(path, "/api/v3")
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	params := make(map[string]string)
+	for idx, segment := range patternSegments {
+		if name, ok := strings.CutSuffix(segment, "...}"); ok && strings.HasPrefix(name, "{") {
+			if idx != len(patternSegments)-1 || idx >= len(pathSegments) {
+				return nil, false
+			}
+			params[name[1:]] = strings.Join(pathSegments[idx:], "/")
+			return params, true
+		}
+		if idx >= len(pathSegments) {
+			return nil, false
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[idx] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = pathSegments[idx]
+			continue
+		}
+		if segment != pathSegments[idx] {
+			return nil, false
+		}
+	}
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+	return params, true
+}
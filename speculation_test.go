@@ -0,0 +1,96 @@
+package ghtransport
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_matchPattern(t *testing.T) {
+	tests := map[string]struct {
+		Pattern  string
+		Path     string
+		Expected bool
+	}{
+		"literal":          {Pattern: "/rate_limit", Path: "/rate_limit", Expected: true},
+		"literal_mismatch": {Pattern: "/rate_limit", Path: "/meta", Expected: false},
+		"param":            {Pattern: "/search/{kind}", Path: "/search/issues", Expected: true},
+		"param_empty":      {Pattern: "/search/{kind}", Path: "/search/", Expected: false},
+		"param_short":      {Pattern: "/repos/{owner}/{repo}/actions/runs", Path: "/repos/foo/bar", Expected: false},
+		"param_long":       {Pattern: "/search/{kind}", Path: "/search/issues/extra", Expected: false},
+		"enterprise":       {Pattern: "/search/{kind}", Path: "/api/v3/search/code", Expected: true},
+		"remainder":        {Pattern: "/repos/{owner}/{repo}/contents/{path...}", Path: "/repos/foo/bar/contents/a/b/c", Expected: true},
+		"remainder_empty":  {Pattern: "/repos/{owner}/{repo}/contents/{path...}", Path: "/repos/foo/bar/contents", Expected: false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := matchPattern(test.Pattern, test.Path); got != test.Expected {
+				t.Errorf("matchPattern(%q, %q) = %v, want %v", test.Pattern, test.Path, got, test.Expected)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_Speculation(t *testing.T) {
+	const body = `{"total_count":0,"incomplete_results":false,"items":[]}`
+	tr := NewTransport(nil, &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			// Both the search and the `[]` guesses are sent, the search guess matches
+			etags := strings.Split(req.Header.Get("If-None-Match"), ", ")
+			if len(etags) != 2 {
+				t.Errorf("If-None-Match = %q, want 2 candidates", req.Header.Get("If-None-Match"))
+			}
+			return &http.Response{
+				StatusCode: http.StatusNotModified,
+				Header:     http.Header{"Etag": []string{etags[0]}},
+				Body:       io.NopCloser(strings.NewReader("")),
+			}, nil
+		},
+	})
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/search/issues?q=is:open", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if got, _ := io.ReadAll(resp.Body); string(got) != body {
+		t.Errorf("RoundTrip() body = %q, want %q", got, body)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("RoundTrip() status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got, want := resp.Header.Get("Cache-Status"), cacheStatusOperation(cacheStatusHitSpeculative(CacheName, "empty-search"), "search/issues-and-pull-requests"); got != want {
+		t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, want)
+	}
+}
+
+func TestTransport_RoundTrip_NoSpeculations(t *testing.T) {
+	tr := New(Options{
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				if _, ok := req.Header["If-None-Match"]; ok {
+					t.Errorf("If-None-Match = %q, want none", req.Header.Get("If-None-Match"))
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Etag": []string{`"live"`}},
+					Body:       io.NopCloser(strings.NewReader("[]")),
+				}, nil
+			},
+		},
+		Speculations: []Speculation{},
+	})
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar/issues", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,138 @@
+package ghtransport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// age calculates the age of a cached response from its "Date" header, per RFC 9111 4.2.3.
+// If the age cannot be determined, ok is false.
+func (t *Transport) age(cached *http.Response) (_ time.Duration, ok bool) {
+	date, err := http.ParseTime(cached.Header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+	return max(t.now().Sub(date), 0), true
+}
+
+// cachedResponse builds a response to req directly from the cached response, without any upstream request.
+// The internal X-Varied-* (and similar) headers and those not allowed by Options.ReplayHeaders are removed
+// and an "Age" header is added per RFC 9111 5.1.
+func (t *Transport) cachedResponse(req *http.Request, cached *http.Response) *http.Response {
+	resp := &http.Response{
+		Status:     cached.Status,
+		StatusCode: cached.StatusCode,
+		Proto:      cached.Proto,
+		ProtoMajor: cached.ProtoMajor,
+		ProtoMinor: cached.ProtoMinor,
+		Header:     maps.Clone(cached.Header),
+		Request:    req,
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	for key := range resp.Header {
+		if !t.replayHeader(key) {
+			delete(resp.Header, key) // These are "internal" to the cache or not replayed per ReplayHeaders
+		}
+	}
+	if vals, ok := cached.Header["X-Github-Request-Id"]; ok {
+		resp.Header[CachedRequestIDHeader] = vals
+	}
+	t.noteCached(req, cached)
+	if age, ok := t.age(cached); ok {
+		resp.Header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	}
+
+	// As a special case, if the request is a HEAD, we return an empty body
+	if req.Method == http.MethodHead {
+		resp.Body = io.NopCloser(strings.NewReader(""))
+		resp.ContentLength = 0
+	} else {
+		resp.Body = cached.Body
+		resp.ContentLength = cached.ContentLength
+	}
+	return resp
+}
+
+// staleIfError returns the cached response if it may be served in place of a failed upstream request,
+// per RFC 5861's stale-if-error, otherwise it returns nil. Only a cached response matching the request's vary
+// headers exactly may be served, otherwise another principal could get a response it cannot access. As with a
+// fresh response, the caller's own validators are evaluated against it.
+func (t *Transport) staleIfError(req *http.Request, cached *http.Response) *http.Response {
+	if cached == nil || t.opts.StaleIfError <= 0 || req.Context().Err() != nil || !t.identicalVary(req, cached) {
+		return nil
+	}
+	// The staleness is measured from the last successful validation, not from when it was stored
+	if age, ok := t.validatedAge(cached); !ok || age > t.opts.StaleIfError {
+		return nil
+	}
+	return t.freshResponse(req, cached, cacheStatusDetail(cacheStatusHit(t.opts.CacheName), "stale-if-error"))
+}
+
+// staleWhileRevalidate revalidates the cached response, but if that does not complete within the
+// RevalidateTimeout, the cached response is returned immediately while the revalidation (and storage
+// of any modified response) continues in the background, per RFC 5861's stale-while-revalidate.
+// The cached response must match the request's vary headers exactly. If not nil, release is called once
+// the revalidation completes.
+func (t *Transport) staleWhileRevalidate(req *http.Request, cached *http.Response, release func()) (*http.Response, error) {
+	// The cached body is needed by both the revalidation and (potentially) the stale response
+	body, err := io.ReadAll(cached.Body)
+	_ = cached.Body.Close()
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
+	}
+	revalidating := *cached
+	revalidating.Body = io.NopCloser(bytes.NewReader(body))
+	stale := *cached
+	stale.Body = io.NopCloser(bytes.NewReader(body))
+
+	// The revalidation must not be canceled if the caller gives up waiting for it, and collects its own
+	// CacheInfo as it may outlive the request
+	ctx, info := withCacheInfo(context.WithoutCancel(req.Context()))
+	done := make(chan roundTripResult, 1)
+	go func() {
+		if release != nil {
+			defer release()
+		}
+		resp, err := t.revalidate(req.Clone(ctx), &revalidating, false)
+		done <- roundTripResult{resp, err}
+	}()
+
+	timer := time.NewTimer(t.opts.RevalidateTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		if dst := cacheInfoFrom(req.Context()); dst != nil {
+			*dst = *info
+		}
+		return r.resp, r.err
+	case <-timer.C:
+		go discardResult(done)
+		return t.freshResponse(req, &stale, cacheStatusDetail(cacheStatusHit(t.opts.CacheName), "stale-while-revalidate")), nil
+	case <-req.Context().Done():
+		go discardResult(done)
+		return nil, req.Context().Err()
+	}
+}
+
+// roundTripResult is the outcome of a RoundTrip performed in another goroutine.
+type roundTripResult struct {
+	resp *http.Response
+	err  error
+}
+
+// discardResult waits for a result nobody is interested in anymore, consuming and closing the response body.
+func discardResult(done <-chan roundTripResult) {
+	discardResponse((<-done).resp)
+}
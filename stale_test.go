@@ -0,0 +1,312 @@
+package ghtransport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransport_RoundTrip_StaleIfError(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		StaleIfError    time.Duration
+		CachedDate      time.Time
+		CachedValidated time.Time
+		CachedVaried    string
+		Authorization   string
+		IfNoneMatch     string
+		Upstream        func(*http.Request) (*http.Response, error)
+		WantErr         bool
+		WantStatusCode  int
+		WantBody        string
+		WantAge         string
+		WantCacheStatus string
+	}{
+		"error": {
+			StaleIfError: time.Hour,
+			CachedDate:   now.Add(-10 * time.Minute),
+			Upstream: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection reset")
+			},
+			WantStatusCode:  http.StatusOK,
+			WantBody:        "cached content",
+			WantAge:         "600",
+			WantCacheStatus: cacheStatusDetail(cacheStatusHit(CacheName), "stale-if-error"),
+		},
+		"error_not_modified": {
+			StaleIfError: time.Hour,
+			CachedDate:   now.Add(-10 * time.Minute),
+			IfNoneMatch:  `"tag1"`,
+			Upstream: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection reset")
+			},
+			WantStatusCode:  http.StatusNotModified,
+			WantAge:         "600",
+			WantCacheStatus: cacheStatusDetail(cacheStatusHit(CacheName), "stale-if-error"),
+		},
+		"unicorn": {
+			StaleIfError: time.Hour,
+			CachedDate:   now.Add(-10 * time.Minute),
+			Upstream: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusBadGateway,
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader("<html>Unicorn!</html>")),
+				}, nil
+			},
+			WantStatusCode:  http.StatusOK,
+			WantBody:        "cached content",
+			WantAge:         "600",
+			WantCacheStatus: cacheStatusDetail(cacheStatusHit(CacheName), "stale-if-error"),
+		},
+		"too_stale": {
+			StaleIfError: time.Minute,
+			CachedDate:   now.Add(-10 * time.Minute),
+			Upstream: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusBadGateway,
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader("<html>Unicorn!</html>")),
+				}, nil
+			},
+			WantStatusCode:  http.StatusBadGateway,
+			WantBody:        "<html>Unicorn!</html>",
+			WantCacheStatus: cacheStatusForward(CacheName, "stale", http.StatusBadGateway, false),
+		},
+		"revalidated": {
+			StaleIfError:    time.Hour,
+			CachedDate:      now.Add(-72 * time.Hour),
+			CachedValidated: now.Add(-time.Minute),
+			Upstream: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection reset")
+			},
+			WantStatusCode:  http.StatusOK,
+			WantBody:        "cached content",
+			WantAge:         "259200",
+			WantCacheStatus: cacheStatusDetail(cacheStatusHit(CacheName), "stale-if-error"),
+		},
+		"other_principal": {
+			StaleIfError:  time.Hour,
+			CachedDate:    now.Add(-10 * time.Minute),
+			CachedVaried:  HashToken("Bearer A"),
+			Authorization: "Bearer B",
+			Upstream: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection reset")
+			},
+			WantErr: true,
+		},
+		"same_principal": {
+			StaleIfError:  time.Hour,
+			CachedDate:    now.Add(-10 * time.Minute),
+			CachedVaried:  HashToken("Bearer A"),
+			Authorization: "Bearer A",
+			Upstream: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection reset")
+			},
+			WantStatusCode:  http.StatusOK,
+			WantBody:        "cached content",
+			WantAge:         "600",
+			WantCacheStatus: cacheStatusDetail(cacheStatusHit(CacheName), "stale-if-error"),
+		},
+		"disabled": {
+			CachedDate: now.Add(-10 * time.Minute),
+			Upstream: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection reset")
+			},
+			WantErr: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tr := New(Options{
+				Storage: &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						header := http.Header{
+							"Date":                []string{test.CachedDate.Format(http.TimeFormat)},
+							"Etag":                []string{`"tag1"`},
+							"X-Github-Request-Id": []string{"CACHED"},
+						}
+						if !test.CachedValidated.IsZero() {
+							header.Set(ValidatedAtHeader, test.CachedValidated.Format(http.TimeFormat))
+						}
+						if test.CachedVaried != "" {
+							header.Set("Vary", "Authorization")
+							header.Set(VaryPrefix+"Authorization", test.CachedVaried)
+						}
+						return &http.Response{
+							StatusCode:    http.StatusOK,
+							Status:        "200 OK",
+							Header:        header,
+							Body:          io.NopCloser(strings.NewReader("cached content")),
+							ContentLength: 14,
+						}, nil
+					},
+				},
+				Parent:       &mockRoundTripper{roundTripFunc: test.Upstream},
+				StaleIfError: test.StaleIfError,
+			})
+			tr.now = func() time.Time { return now }
+
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if test.Authorization != "" {
+				req.Header.Set("Authorization", test.Authorization)
+			}
+			if test.IfNoneMatch != "" {
+				req.Header.Set("If-None-Match", test.IfNoneMatch)
+			}
+
+			resp, err := tr.RoundTrip(req)
+			if (err != nil) != test.WantErr {
+				t.Fatalf("RoundTrip() error = %v, wantErr %v", err, test.WantErr)
+			}
+			if test.WantErr {
+				return
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.WantStatusCode {
+				t.Errorf("RoundTrip() status = %v, want %v", resp.StatusCode, test.WantStatusCode)
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != test.WantBody {
+				t.Errorf("RoundTrip() body = %q, want %q", body, test.WantBody)
+			}
+			if got := resp.Header.Get("Age"); got != test.WantAge {
+				t.Errorf("RoundTrip() %s = %q, want %q", "Age", got, test.WantAge)
+			}
+			if got, want := resp.Header.Get("Cache-Status"), withOperation(test.WantCacheStatus); got != want {
+				t.Errorf("RoundTrip() %s = %q, want %q", "Cache-Status", got, want)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_StaleWhileRevalidate(t *testing.T) {
+	tests := map[string]struct {
+		Delay           time.Duration
+		Sleep           time.Duration
+		CachedVaried    string
+		Authorization   string
+		IfNoneMatch     string
+		WantStatusCode  int
+		WantBody        string
+		WantCacheStatus string
+	}{
+		"fast": {
+			Delay:           0,
+			WantBody:        "new content",
+			WantCacheStatus: cacheStatusForward(CacheName, "stale", http.StatusOK, true),
+		},
+		"slow": {
+			Delay:           time.Hour,
+			WantBody:        "cached content",
+			WantCacheStatus: cacheStatusDetail(cacheStatusHit(CacheName), "stale-while-revalidate"),
+		},
+		"slow_not_modified": {
+			Delay:           time.Hour,
+			IfNoneMatch:     `"tag1"`,
+			WantStatusCode:  http.StatusNotModified,
+			WantCacheStatus: cacheStatusDetail(cacheStatusHit(CacheName), "stale-while-revalidate"),
+		},
+		"slow_other_principal": {
+			Sleep:           50 * time.Millisecond,
+			CachedVaried:    HashToken("Bearer A"),
+			Authorization:   "Bearer B",
+			WantBody:        "new content",
+			WantCacheStatus: cacheStatusForward(CacheName, "stale", http.StatusOK, true),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			release := make(chan struct{})
+			stored := make(chan string, 1)
+			tr := New(Options{
+				Storage: &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						header := http.Header{
+							"Etag": []string{`"tag1"`},
+						}
+						if test.CachedVaried != "" {
+							header.Set("Vary", "Authorization")
+							header.Set(VaryPrefix+"Authorization", test.CachedVaried)
+						}
+						return &http.Response{
+							StatusCode:    http.StatusOK,
+							Status:        "200 OK",
+							Header:        header,
+							Body:          io.NopCloser(strings.NewReader("cached content")),
+							ContentLength: 14,
+						}, nil
+					},
+					putFunc: func(ctx context.Context, resp *http.Response) error {
+						stored <- resp.Header.Get("Etag")
+						return nil
+					},
+				},
+				Parent: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						if test.Delay > 0 {
+							<-release
+						}
+						time.Sleep(test.Sleep)
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Header:     http.Header{"Etag": []string{`"tag2"`}},
+							Body:       io.NopCloser(strings.NewReader("new content")),
+						}, nil
+					},
+				},
+				RevalidateTimeout: 10 * time.Millisecond,
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if test.Authorization != "" {
+				req.Header.Set("Authorization", test.Authorization)
+			}
+			if test.IfNoneMatch != "" {
+				req.Header.Set("If-None-Match", test.IfNoneMatch)
+			}
+
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			defer resp.Body.Close()
+
+			wantStatusCode := test.WantStatusCode
+			if wantStatusCode == 0 {
+				wantStatusCode = http.StatusOK
+			}
+			if resp.StatusCode != wantStatusCode {
+				t.Errorf("RoundTrip() status = %d, want %d", resp.StatusCode, wantStatusCode)
+			}
+
+			if body, _ := io.ReadAll(resp.Body); string(body) != test.WantBody {
+				t.Errorf("RoundTrip() body = %q, want %q", body, test.WantBody)
+			}
+			if got, want := resp.Header.Get("Cache-Status"), withOperation(test.WantCacheStatus); got != want {
+				t.Errorf("RoundTrip() %s = %q, want %q", "Cache-Status", got, want)
+			}
+
+			// Either way, the modified response must eventually be stored
+			close(release)
+			select {
+			case etag := <-stored:
+				if etag != `"tag2"` {
+					t.Errorf("(Storage).Put Etag = %q, want %q", etag, `"tag2"`)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("(Storage).Put was not called")
+			}
+		})
+	}
+}
@@ -14,3 +14,57 @@ type Storage interface {
 	// If no error is returned, the consumed (*http.Response).Body must be replaced/restored.
 	Put(context.Context, *http.Response) error
 }
+
+// Deleter is an optional interface a Storage may implement to remove cached HTTP responses, such as
+// variants evicted beyond Options.MaxVariants. Otherwise they are simply no longer used.
+type Deleter interface {
+	// Deletes the cached HTTP response (if any) from storage for the given (*http.Request).URL.
+	Delete(context.Context, *http.Request) error
+}
+
+// Leaser is an optional interface a Storage shared by multiple processes may implement to coordinate
+// revalidation, such that only one of them performs the upstream request for a given URL at a time.
+type Leaser interface {
+	// Attempts to acquire the revalidation lease for the given (*http.Request).URL.
+	// If the lease is already held by someone else, it must return (nil, nil).
+	Lease(context.Context, *http.Request) (release func(context.Context) error, err error)
+	// Blocks until the revalidation lease for the given (*http.Request).URL is released or has expired.
+	Wait(context.Context, *http.Request) error
+}
+
+// StorageErrorPolicy determines how a Transport handles errors returned by its Storage.
+type StorageErrorPolicy int
+
+const (
+	// FailClosed returns any Storage error from RoundTrip, this is the default.
+	FailClosed StorageErrorPolicy = iota
+	// FailOpen ignores Storage errors, degrading to a plain upstream request. The failure is still
+	// reported via Options.OnStorageError and reflected in the "Cache-Status" header.
+	FailOpen
+)
+
+// StorageError records a failed Storage operation.
+type StorageError struct {
+	Op  string // "Get", "Put", "Delete", "Lease", "Wait" or "Release"
+	Err error
+}
+
+func (e *StorageError) Error() string {
+	return "(Storage)." + e.Op + " failed: " + e.Err.Error()
+}
+
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
+
+// storageError reports a failed Storage operation, returning a non-nil error only if the request should fail.
+func (t *Transport) storageError(req *http.Request, op string, err error) error {
+	serr := &StorageError{Op: op, Err: err}
+	if t.opts.OnStorageError != nil {
+		t.opts.OnStorageError(req, serr)
+	}
+	if t.opts.StorageErrorPolicy == FailOpen {
+		return nil
+	}
+	return serr
+}
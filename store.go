@@ -0,0 +1,151 @@
+package ghtransport
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// varied builds the fake X-Varied-<header> "response" headers recording the values of the request headers
+// listed in the "Vary" response header, these are used to determine if a cached response can be reused as-is.
+func (t *Transport) varied(req *http.Request, respHeader http.Header) http.Header {
+	varied := make(http.Header)
+	for header := range parseVary(respHeader) {
+		header = http.CanonicalHeaderKey(header)
+		if vals := req.Header.Values(header); len(vals) > 0 {
+			if header == "Authorization" {
+				vals = []string{HashToken(vals[0])} // Don't leak/cache the raw authentication token
+			}
+			varied[t.opts.VaryPrefix+header] = vals
+		}
+	}
+	return varied
+}
+
+// StoredByHeader is the "internal" response header recording the hashed token (see HashToken) of the
+// principal that stored a response.
+const StoredByHeader = "X-Cache-Stored-By"
+
+// APIVersionHeader is the "internal" response header recording the GitHub REST API version a response was
+// stored for.
+const APIVersionHeader = "X-Cache-Api-Version"
+
+// apiVersion determines the GitHub REST API version of the response, preferring the version GitHub reports
+// having selected over the version that was requested.
+func apiVersion(req *http.Request, resp *http.Response) string {
+	if version := resp.Header.Get("X-Github-Api-Version-Selected"); version != "" {
+		return version
+	}
+	return req.Header.Get("X-Github-Api-Version")
+}
+
+// store persists the upstream response to req in the Storage. The response body is read into memory (and
+// restored) so it remains intact even if the Storage fails. If the Storage failed but the StorageErrorPolicy
+// allows the request to proceed, failed is true.
+func (t *Transport) store(req *http.Request, resp *http.Response, cached *http.Response) (stored, failed bool, _ error) {
+	// Make a shallow copy of the *http.Response as we're going to modify the headers for storage
+	cacheResp := *resp
+	cacheResp.Request = req
+	cacheResp.Header = maps.Clone(resp.Header)
+
+	// Remove any headers the route or the PersistHeaders policy does not keep
+	for _, key := range t.route(req).DropHeaders {
+		cacheResp.Header.Del(key)
+	}
+	for key := range cacheResp.Header {
+		if !t.persistHeader(key) {
+			delete(cacheResp.Header, key)
+		}
+	}
+
+	// Inject fake X-Varied-<header> "response" headers
+	maps.Copy(cacheResp.Header, t.varied(req, resp.Header))
+
+	// Record the provenance of the response: when (to determine its freshness), by whom and for which API version
+	cacheResp.Header.Set(StoredAtHeader, t.now().UTC().Format(http.TimeFormat))
+	cacheResp.Header.Set(StoredByHeader, HashToken(req.Header.Get("Authorization")))
+	if version := apiVersion(req, resp); version != "" {
+		cacheResp.Header.Set(APIVersionHeader, version)
+	}
+
+	// Read the response body into memory, so it can be restored even if the Storage fails
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return false, false, fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	cacheResp.Body = io.NopCloser(bytes.NewReader(body))
+	cacheResp.ContentLength = int64(len(body))
+
+	// If multiple variants are kept per URL, the response is stored both as a variant and the primary response
+	if t.opts.MaxVariants > 1 {
+		if err := t.storeVariant(req, &cacheResp, body, cached); err != nil {
+			if err := t.storageError(req, "Put", err); err != nil {
+				return false, false, err
+			}
+			return false, true, nil
+		}
+	}
+
+	// Store the cached response body as bytes
+	if err := t.opts.Storage.Put(req.Context(), &cacheResp); err != nil {
+		if err := t.storageError(req, "Put", err); err != nil {
+			return false, false, err
+		}
+		return false, true, nil
+	}
+	return true, false, nil
+}
+
+// refresh re-stores the stored response chosen to answer a 304 Not Modified with an updated
+// ValidatedAtHeader, per RFC 9111 4.3.4, such that its freshness is measured from the validation rather than
+// from when it was stored. It is re-stored wherever it was read from: the primary response for the URL and/or
+// its variant (see Options.MaxVariants), cached is the primary (or selected) cached response. The chosen
+// response body is read into memory (and restored).
+func (t *Transport) refresh(req *http.Request, chosen, cached *http.Response) error {
+	if t.opts.Storage == nil || directivesFrom(req.Context()).NoStore {
+		return nil
+	}
+	body, err := io.ReadAll(chosen.Body)
+	_ = chosen.Body.Close()
+	if err != nil {
+		return fmt.Errorf("(*http.Response).Body.Read failed: %w", err)
+	}
+	chosen.Body = io.NopCloser(bytes.NewReader(body))
+	chosen.ContentLength = int64(len(body))
+	chosen.Header = maps.Clone(chosen.Header)
+	chosen.Header.Set(ValidatedAtHeader, t.now().UTC().Format(http.TimeFormat))
+
+	// Determine where the chosen response was read from
+	targets := []*http.Request{req}
+	if t.opts.MaxVariants > 1 {
+		targets = nil
+		id, ids := variantID(t.storedVaried(chosen.Header)), variants(cached.Header)
+		if slices.Contains(ids, id) {
+			targets = append(targets, variantRequest(req, id))
+		}
+		if len(ids) == 0 || ids[0] == id {
+			targets = append(targets, req) // The primary response is a copy of the most recent variant
+		}
+	}
+
+	for _, target := range targets {
+		cacheResp := *chosen
+		cacheResp.Request = target
+		cacheResp.Header = maps.Clone(chosen.Header)
+		if target == req && cached.Header[VariantsHeader] != nil {
+			cacheResp.Header[VariantsHeader] = cached.Header[VariantsHeader] // Maintained on the primary response
+		}
+		cacheResp.Body = io.NopCloser(bytes.NewReader(body))
+		cacheResp.ContentLength = int64(len(body))
+		if err := t.opts.Storage.Put(req.Context(), &cacheResp); err != nil {
+			return t.storageError(req, "Put", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package ghtransport
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TokenPool is a http.RoundTripper spreading requests across a pool of tokens (ex: personal access tokens
+// and GitHub App installation tokens) by setting the "Authorization" header of each request before passing
+// it to the Transport. As the ETag is recalculated per token (see HashToken), a response stored by one token
+// can still be revalidated by another.
+//
+// The token is chosen per the rate limit state tracked by the Transport: a token with a stored response for
+// the request whose vary headers match exactly (such that its ETag is reused as-is) is preferred, otherwise
+// the token with the most remaining quota for the request's rate limit resource. Tokens serving a Penalty
+// for that resource are skipped, unless every token is. Requests that already carry an "Authorization"
+// header are passed through as-is.
+type TokenPool struct {
+	// Transport performs the requests and tracks the rate limit state of each token.
+	Transport *Transport
+	// Tokens are the "Authorization" header values of the pool (ex: "Bearer ghp_...").
+	Tokens []string
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (p *TokenPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" || len(p.Tokens) == 0 {
+		return p.Transport.RoundTrip(req)
+	}
+	// Per the http.RoundTripper contract, we cannot modify the request in-place, we need to shallow clone it.
+	// Any stored response read to choose the token is handed to the Transport, rather than read again.
+	peek := &peeked{}
+	defer peek.discard()
+	req = req.Clone(context.WithValue(req.Context(), peekedKey{}, peek))
+	req.Header.Set("Authorization", p.choose(req, peek))
+	return p.Transport.RoundTrip(req)
+}
+
+// choose picks the token for the request, see TokenPool.
+func (p *TokenPool) choose(req *http.Request, peek *peeked) string {
+	t := p.Transport
+	now := t.now()
+
+	// Skip the tokens serving a penalty for the resource, unless every token is (then the one that ends first)
+	resource := rateLimitResource(req)
+	var tokens []string
+	var blocked string
+	var first Penalty
+	for _, token := range p.Tokens {
+		penalty, ok := t.Penalty(token, resource)
+		if !ok {
+			tokens = append(tokens, token)
+		} else if blocked == "" || penalty.Until.Before(first.Until) {
+			blocked, first = token, penalty
+		}
+	}
+	switch len(tokens) {
+	case 0:
+		return blocked
+	case 1:
+		return tokens[0]
+	}
+
+	// The remaining quota of a token, unknown (or reset) is treated as the full quota
+	remaining := func(token string) int {
+		limit, ok := t.RateLimit(token, resource)
+		if !ok || !now.Before(limit.Reset) {
+			return math.MaxInt
+		}
+		return limit.Remaining
+	}
+
+	// Prefer a token that has a stored response matching exactly, if it has quota left
+	if token, ok := p.stored(req, tokens, peek); ok && remaining(token) > 0 {
+		return token
+	}
+
+	best := tokens[0]
+	for _, token := range tokens[1:] {
+		if remaining(token) > remaining(best) {
+			best = token
+		}
+	}
+	return best
+}
+
+// stored returns the first token with a stored response (or variant) for the request whose vary headers
+// match exactly. The stored response is kept in peek for the Transport. Any Storage error is ignored here,
+// the Transport will encounter it as well.
+func (p *TokenPool) stored(req *http.Request, tokens []string, peek *peeked) (string, bool) {
+	t := p.Transport
+	if ok, _ := t.cacheable(req); !ok || t.opts.Storage == nil {
+		return "", false
+	}
+	cached, err := t.opts.Storage.Get(req.Context(), req)
+	if err != nil {
+		return "", false
+	}
+	peek.put(cached)
+	if cached == nil {
+		return "", false
+	}
+	if !slices.ContainsFunc(slices.Collect(parseVary(cached.Header)), func(header string) bool {
+		return strings.EqualFold(header, "Authorization")
+	}) {
+		return "", false // Any token would do
+	}
+	ids := variants(cached.Header)
+	for _, token := range tokens {
+		candidate := req.Clone(req.Context())
+		candidate.Header.Set("Authorization", token)
+		if t.identicalVary(candidate, cached) {
+			return token, true
+		}
+		if t.opts.MaxVariants > 1 && slices.Contains(ids, variantID(t.varied(candidate, cached.Header))) {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// peekedKey is the context.Context key of the *peeked stored response of a request.
+type peekedKey struct{}
+
+// peeked is the stored response a TokenPool read for a request, handed to the Transport such that it is not
+// read from the Storage twice. Whoever takes it first owns it, if nobody does it is discarded.
+type peeked struct {
+	mu    sync.Mutex
+	resp  *http.Response
+	ok    bool
+	taken bool
+}
+
+// put records the stored response (nil if nothing was stored).
+func (p *peeked) put(resp *http.Response) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resp, p.ok = resp, true
+}
+
+// discard consumes and closes the stored response, unless it was taken.
+func (p *peeked) discard() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.taken {
+		p.taken = true
+		discardResponse(p.resp)
+	}
+}
+
+// takePeeked takes the stored response a TokenPool read for the request, ok is false if there is none (or it
+// was already taken) and the request must read it from the Storage instead.
+func takePeeked(req *http.Request) (_ *http.Response, ok bool) {
+	p, _ := req.Context().Value(peekedKey{}).(*peeked)
+	if p == nil {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.ok || p.taken {
+		return nil, false
+	}
+	p.taken = true
+	return p.resp, true
+}
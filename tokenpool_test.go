@@ -0,0 +1,154 @@
+package ghtransport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenPool_RoundTrip(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		Name          string
+		Remaining     map[string]int
+		Penalized     map[string]time.Duration
+		Resource      string
+		StoredBy      string
+		Authorization string
+		Path          string
+		Expected      string
+	}{
+		{
+			Name:      "most remaining",
+			Remaining: map[string]int{"Bearer a": 100, "Bearer b": 4000, "Bearer c": 2000},
+			Expected:  "Bearer b",
+		},
+		{
+			Name:      "unknown is full quota",
+			Remaining: map[string]int{"Bearer a": 100, "Bearer b": 4000},
+			Expected:  "Bearer c",
+		},
+		{
+			Name:      "per resource",
+			Remaining: map[string]int{"Bearer a": 100, "Bearer b": 4000, "Bearer c": 2000},
+			Path:      "/search/issues",
+			Expected:  "Bearer a", // Nothing known about the search resource of any token
+		},
+		{
+			Name:      "skip penalized",
+			Remaining: map[string]int{"Bearer a": 100, "Bearer b": 4000, "Bearer c": 2000},
+			Penalized: map[string]time.Duration{"Bearer b": time.Minute},
+			Expected:  "Bearer c",
+		},
+		{
+			Name:      "penalized for another resource",
+			Remaining: map[string]int{"Bearer a": 100, "Bearer b": 4000, "Bearer c": 2000},
+			Penalized: map[string]time.Duration{"Bearer b": time.Minute},
+			Resource:  "search",
+			Expected:  "Bearer b",
+		},
+		{
+			Name:      "all penalized",
+			Remaining: map[string]int{"Bearer a": 100, "Bearer b": 4000, "Bearer c": 2000},
+			Penalized: map[string]time.Duration{"Bearer a": time.Minute, "Bearer b": time.Hour, "Bearer c": 30 * time.Second},
+			Expected:  "Bearer c",
+		},
+		{
+			Name:      "stored",
+			Remaining: map[string]int{"Bearer a": 100, "Bearer b": 4000, "Bearer c": 2000},
+			StoredBy:  "Bearer a",
+			Expected:  "Bearer a",
+		},
+		{
+			Name:      "stored without quota",
+			Remaining: map[string]int{"Bearer a": 0, "Bearer b": 4000, "Bearer c": 2000},
+			StoredBy:  "Bearer a",
+			Expected:  "Bearer b",
+		},
+		{
+			Name:      "stored but penalized",
+			Remaining: map[string]int{"Bearer a": 100, "Bearer b": 4000, "Bearer c": 2000},
+			Penalized: map[string]time.Duration{"Bearer a": time.Minute},
+			StoredBy:  "Bearer a",
+			Expected:  "Bearer b",
+		},
+		{
+			Name:          "caller authorization",
+			Remaining:     map[string]int{"Bearer a": 100, "Bearer b": 4000, "Bearer c": 2000},
+			Authorization: "Bearer mine",
+			Expected:      "Bearer mine",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var got string
+			var gets int
+			tr := New(Options{
+				Storage: &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						gets++
+						if test.StoredBy == "" {
+							return nil, nil
+						}
+						return &http.Response{
+							StatusCode: http.StatusOK,
+							Header: http.Header{
+								"Etag":                   []string{`"tag1"`},
+								"Vary":                   []string{"Accept, Authorization, Cookie, X-GitHub-OTP"},
+								"X-Varied-Authorization": []string{HashToken(test.StoredBy)},
+							},
+							Body: io.NopCloser(strings.NewReader("content")),
+						}, nil
+					},
+				},
+				Parent: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						got = req.Header.Get("Authorization")
+						return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("content"))}, nil
+					},
+				},
+			})
+			tr.now = func() time.Time { return now }
+			tr.rateLimits.limits = make(map[rateLimitKey]RateLimit)
+			for token, remaining := range test.Remaining {
+				key := rateLimitKey{principal: HashToken(token), resource: "core"}
+				tr.rateLimits.limits[key] = RateLimit{Principal: key.principal, Resource: "core", Limit: 5000, Remaining: remaining, Reset: now.Add(time.Hour)}
+			}
+			tr.penalties.penalties = make(map[rateLimitKey]Penalty)
+			for token, d := range test.Penalized {
+				key := rateLimitKey{principal: HashToken(token), resource: test.Resource}
+				tr.penalties.penalties[key] = Penalty{Principal: key.principal, Resource: key.resource, Until: now.Add(d)}
+			}
+			pool := &TokenPool{Transport: tr, Tokens: []string{"Bearer a", "Bearer b", "Bearer c"}}
+
+			path := test.Path
+			if path == "" {
+				path = "/repos/foo/bar"
+			}
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com"+path, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if test.Authorization != "" {
+				req.Header.Set("Authorization", test.Authorization)
+			}
+			resp, err := pool.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			resp.Body.Close()
+			if got != test.Expected {
+				t.Errorf("RoundTrip() used %q, want %q", got, test.Expected)
+			}
+			if gets != 1 {
+				t.Errorf("RoundTrip() read the Storage %d times, want 1", gets)
+			}
+			if test.Authorization == "" && req.Header.Get("Authorization") != "" {
+				t.Error("RoundTrip() modified the request in-place")
+			}
+		})
+	}
+}
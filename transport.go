@@ -1,35 +1,53 @@
 package ghtransport
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"maps"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/bored-engineer/github-conditional-http-transport/openapi"
 )
 
 // CachedRequestIDHeader is the X-Github-Request-Id header from the cached response.
 const CachedRequestIDHeader = "X-Cached-Request-Id"
 
-// CacheName identifies this cache in the "Cache-Status" header, per RFC 9211. It may be overridden
-// (e.g. by an application embedding this transport under its own name) before use.
+// CacheName is the default name identifying this cache in the "Cache-Status" header, per RFC 9211.
+// It may be overridden (e.g. by an application embedding this transport under its own name) before
+// calling New, or per Transport via Options.CacheName.
 var CacheName = "github-conditional-http-transport"
 
 // cacheStatusHit builds the "Cache-Status" header value for a cache hit.
-func cacheStatusHit() string {
-	return CacheName + `; hit`
+func cacheStatusHit(name string) string {
+	return name + `; hit`
 }
 
 // cacheStatusHitSpeculative builds the "Cache-Status" header value for a "hit" that was the result of a
-// speculative `[]` ETag guess (i.e. no response was ever actually stored for this request), per RFC
-// 9211's optional "detail" parameter for conveying implementation-specific information.
-func cacheStatusHitSpeculative() string {
-	return cacheStatusHit() + `; detail=speculative-empty-array`
+// speculative ETag guess (i.e. no response was ever actually stored for this request), per RFC 9211's
+// optional "detail" parameter for conveying implementation-specific information.
+func cacheStatusHitSpeculative(name, speculation string) string {
+	return cacheStatusDetail(cacheStatusHit(name), "speculative-"+speculation)
+}
+
+// Transport is a http.RoundTripper that reads/writes GitHub REST API responses from a Storage,
+// revalidating them via conditional requests. It is safe for concurrent use.
+type Transport struct {
+	opts       Options
+	now        func() time.Time
+	sleep      func(context.Context, time.Duration) error
+	flights    flights
+	rateLimits rateLimits
+	pacer      pacer
+	penalties  penalties
+	budgets    budgets
 }
 
-type transport struct {
-	storage Storage
-	parent  http.RoundTripper
+// cacheStatusDetail appends the RFC 9211 "detail" parameter to a "Cache-Status" header value.
+func cacheStatusDetail(cacheStatus, detail string) string {
+	return cacheStatus + "; detail=" + detail
 }
 
 // setCacheStatus sets the "Cache-Status"/"X-Cache" header pair on resp, initializing resp.Header if necessary.
@@ -44,62 +62,285 @@ func setCacheStatus(resp *http.Response, cacheStatus, xCache string) {
 // cacheStatusForward builds the "Cache-Status" header value for a request that was forwarded upstream
 // (i.e. not a cache hit), recording the reason (per RFC 9211's fwd parameter), the resulting upstream
 // status code (fwd-status), and whether the response was stored for future requests.
-func cacheStatusForward(reason string, statusCode int, stored bool) string {
-	v := fmt.Sprintf("%s; fwd=%s; fwd-status=%d", CacheName, reason, statusCode)
+func cacheStatusForward(name, reason string, statusCode int, stored bool) string {
+	v := fmt.Sprintf("%s; fwd=%s; fwd-status=%d", name, reason, statusCode)
 	if stored {
 		v += "; stored"
 	}
 	return v
 }
 
+// discardResponse consumes and closes the body of the (optional) response, so the connection can be re-used.
+func discardResponse(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+}
+
+// cacheStatusOperation appends the (non-standard) "operation" parameter to a "Cache-Status" header value,
+// identifying the GitHub REST API operation of the request (ex: "repos/get").
+func cacheStatusOperation(cacheStatus, operation string) string {
+	return cacheStatus + "; operation=" + operation
+}
+
 // RoundTrip implements the http.RoundTripper interface.
-func (t *transport) RoundTrip(req *http.Request) (resp *http.Response, _ error) {
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Collect the details of how the request is handled as it goes, see Info
+	ctx, info := withCacheInfo(req.Context())
+	req = req.WithContext(ctx)
+
+	resp, err := t.handle(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identify the GitHub REST API operation the response belongs to
+	if op, ok := openapi.Lookup(req.Method, req.URL.Path); ok {
+		if cacheStatus := resp.Header.Get("Cache-Status"); cacheStatus != "" {
+			resp.Header.Set("Cache-Status", cacheStatusOperation(cacheStatus, op.ID))
+		}
+	}
+
+	// The rest of the details are described by the "Cache-Status" header
+	if statuses := ParseCacheStatus(resp.Header.Get("Cache-Status")); len(statuses) > 0 {
+		status := statuses[len(statuses)-1]
+		status.StoredAt, status.StoredBy, status.APIVersion = info.StoredAt, info.StoredBy, info.APIVersion
+		status.RequestID, status.CachedRequestID, status.Variant = info.RequestID, info.CachedRequestID, info.Variant
+		*info = status
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// handle implements RoundTrip, returning the response before the operation is identified.
+func (t *Transport) handle(req *http.Request) (*http.Response, error) {
+	// Any directives of the "Cache-Control" request header apply to this request only, they are not sent upstream
+	req = withDirectives(req)
+	directives := directivesFrom(req.Context())
+
+	// In offline mode, the request may only be answered from storage
+	if t.onlyIfCached(req) {
+		return t.offline(req)
+	}
+
 	// If the request is not cacheable, just pass it through to the parent RoundTripper
-	if ok, reason := cacheable(req); !ok {
-		resp, err := t.parent.RoundTrip(req)
+	if ok, reason := t.cacheable(req); !ok {
+		// Requests bypassing the cache per their Route (ex: the free "/rate_limit") are not paced nor budgeted
+		bypassed := t.bypassed(req)
+		if !bypassed {
+			if _, err := t.backoff(req, nil); err != nil {
+				return nil, err
+			}
+			if _, err := t.budget(req, nil); err != nil {
+				return nil, err
+			}
+			if err := t.pace(req, true); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := t.opts.Parent.RoundTrip(req)
 		if err != nil {
 			return nil, err
 		}
-		setCacheStatus(resp, cacheStatusForward(reason, resp.StatusCode, false), "MISS")
+		t.noteUpstream(req, resp)
+		if !bypassed {
+			t.noteBudget(req, resp)
+		}
+		t.notePenalty(req, resp)
+		setCacheStatus(resp, cacheStatusForward(t.opts.CacheName, reason, resp.StatusCode, false), "MISS")
 		return resp, nil
 	}
 
-	// Attempt to fetch from storage, if one is configured
-	var cached *http.Response
-	var err error
-	if t.storage != nil {
-		cached, err = t.storage.Get(req.Context(), req)
+	// Concurrent identical requests may share a single upstream request, unless directives make them differ
+	if t.opts.Coalesce && directives == (RequestDirectives{}) {
+		return t.coalesce(req)
+	}
+
+	return t.roundTrip(req)
+}
+
+// load retrieves the cached response for req from storage (if one is configured), selecting the stored variant
+// matching the request. If the Storage failed but the StorageErrorPolicy allows the request to proceed,
+// storageFailed is true.
+func (t *Transport) load(req *http.Request) (cached *http.Response, storageFailed bool, err error) {
+	// Attempt to fetch from storage, if one is configured (unless a TokenPool already did)
+	if t.opts.Storage != nil {
+		var ok bool
+		if cached, ok = takePeeked(req); !ok {
+			cached, err = t.opts.Storage.Get(req.Context(), req)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("(Storage).Get failed: %w", err)
+			if err := t.storageError(req, "Get", err); err != nil {
+				return nil, false, err
+			}
+			// Fail open, treating the failure as if nothing was cached
+			cached, storageFailed = nil, true
+		}
+	}
+
+	// Select the stored variant matching the request, if there are multiple
+	if cached, err = t.selectVariant(req, cached); err != nil {
+		discardResponse(cached)
+		return nil, false, err
+	}
+
+	// Stored responses older than the route's retention are treated as if nothing was stored
+	if route := t.route(req); cached != nil && route.Retention > 0 {
+		if age, ok := t.age(cached); ok && age > time.Duration(route.Retention) {
+			discardResponse(cached)
+			cached = nil
 		}
 	}
+	return cached, storageFailed, nil
+}
+
+// roundTrip handles a cacheable request, retrieving any cached response from storage and revalidating it.
+func (t *Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	cached, storageFailed, err := t.load(req)
+	if err != nil {
+		return nil, err
+	}
+	route := t.route(req)
+	directives := directivesFrom(req.Context())
+
+	// Stored responses of content-addressed requests can never change, so they are always fresh
+	if cached != nil && !directives.NoCache && route.immutable(req) && t.identicalVary(req, cached) {
+		return t.freshResponse(req, cached, cacheStatusDetail(cacheStatusHit(t.opts.CacheName), "immutable")), nil
+	}
+
+	// Stored responses within their freshness lifetime (or the staleness the request accepts) can be served
+	// without revalidation
+	if cached != nil && !directives.NoCache && t.identicalVary(req, cached) {
+		if ttl, ok := t.fresh(route, cached, directives.MaxStale); ok {
+			return t.freshResponse(req, cached, cacheStatusHitTTL(t.opts.CacheName, ttl)), nil
+		}
+	}
+
+	// Coordinate the revalidation with any other processes sharing the storage
+	var release func()
+	if leaser, ok := t.leaser(); ok && t.opts.LeaseWait > 0 && !storageFailed && !directives.NoCache {
+		var waited time.Time
+		waited, release, err = t.lease(req, leaser)
+		if err != nil {
+			discardResponse(cached)
+			return nil, err
+		}
+		if !waited.IsZero() {
+			// Another process held the lease while we waited, re-read whatever it stored
+			discardResponse(cached)
+			if cached, storageFailed, err = t.load(req); err != nil {
+				return nil, err
+			}
+			// Only if it successfully revalidated (or stored a new response) meanwhile, the result is shared
+			if cached != nil && t.identicalVary(req, cached) && validatedSince(cached, waited) {
+				return t.freshResponse(req, cached, cacheStatusHit(t.opts.CacheName)+"; collapsed"), nil
+			}
+		}
+	}
+
+	// If revalidation is latency-bounded, it may need to continue in the background. Only a cached response
+	// matching the request exactly may be served meanwhile, otherwise the revalidation decides.
+	if cached != nil && t.opts.RevalidateTimeout > 0 && !directives.NoCache && t.identicalVary(req, cached) {
+		return t.staleWhileRevalidate(req, cached, release)
+	}
+
+	if release != nil {
+		defer release()
+	}
+	return t.revalidate(req, cached, storageFailed)
+}
+
+// revalidate performs the conditional upstream request for req using the (optional) cached response,
+// storing the upstream response if it was modified. It takes ownership of the cached response.
+func (t *Transport) revalidate(req *http.Request, cached *http.Response, storageFailed bool) (resp *http.Response, err error) {
+	var others []*http.Response
 	defer func() {
-		// If we did not utilize the cached response, ensure it is consumed and closed
-		if cached != nil && cached.Body != nil && (resp == nil || resp.Body != cached.Body) {
-			_, _ = io.Copy(io.Discard, cached.Body)
-			_ = cached.Body.Close()
+		// If we did not utilize a cached response, ensure it is consumed and closed
+		for _, c := range append([]*http.Response{cached}, others...) {
+			if c != nil && c.Body != nil && (resp == nil || resp.Body != c.Body) {
+				_, _ = io.Copy(io.Discard, c.Body)
+				_ = c.Body.Close()
+			}
 		}
 	}()
 
-	// Per the http.RoundTripper contract, we cannot modify the request in-place, we need to shallow clone it
+	// Every other stored variant is a candidate for a 304 Not Modified as well
+	if others, err = t.otherVariants(req, cached); err != nil {
+		return nil, err
+	}
+
+	// Per the http.RoundTripper contract, we cannot modify the request in-place, we need to shallow clone it.
+	// The original keeps the caller's conditional headers, for any stale response served instead.
+	orig := req
 	req = req.Clone(req.Context())
 
 	// If there is a User-Agent, ensure it's compatible
-	replaceUserAgent(req.Header)
+	t.replaceUserAgent(req.Header)
+
+	// Remember (and remove) any conditional headers supplied by the caller, we'll evaluate them ourselves
+	callerValidators := takeValidators(req.Header)
 
 	// Inject the conditional headers to the request
-	if err := addConditionalHeaders(req, cached); err != nil {
+	candidates, err := t.addConditionalHeaders(req, append([]*http.Response{cached}, others...)...)
+	if err != nil {
 		return nil, fmt.Errorf("failed to inject conditional headers: %w", err)
 	}
+	// The caller's own ETags may match as well, in which case the 304 Not Modified is theirs
+	for _, etag := range callerValidators.ifNoneMatch {
+		if _, ok := matchCandidate(candidates, etag); !ok {
+			req.Header.Set("If-None-Match", req.Header.Get("If-None-Match")+", "+etag)
+		}
+	}
+
+	// Hold back while a secondary rate limit penalty is being served, answering any stale response to the caller's request
+	if stale, err := t.backoff(orig, cached); err != nil || stale != nil {
+		return stale, err
+	}
+
+	// Hold back the requests of a caller that exhausted its budget, answering any stale response to the caller's request
+	if stale, err := t.budget(orig, cached); err != nil || stale != nil {
+		return stale, err
+	}
+
+	// Spread the requests that will likely count against the rate limit, a revalidation is likely free
+	likelyFree := cached != nil || len(others) > 0 || len(callerValidators.ifNoneMatch) > 0
+	if err := t.pace(req, !likelyFree); err != nil {
+		return nil, err
+	}
 
 	// Perform the upstream request
-	resp, err = t.parent.RoundTrip(req)
+	resp, err = t.opts.Parent.RoundTrip(req)
 	if err != nil {
+		if stale := t.staleIfError(orig, cached); stale != nil {
+			return stale, nil
+		}
 		return nil, fmt.Errorf("(http.RoundTripper).RoundTrip failed: %w", err)
 	}
+	t.noteUpstream(req, resp)
+	t.noteBudget(req, resp)
+
+	// If a secondary rate limit was hit, we may be able to serve the cached response instead of retrying later
+	if t.notePenalty(req, resp) {
+		if stale := t.staleIfPenalty(orig, cached); stale != nil {
+			discardResponse(resp)
+			return stale, nil
+		}
+	}
+
+	// If GitHub is failing (ex: a 502 "Unicorn" page), we may be able to serve the cached response instead
+	if resp.StatusCode >= http.StatusInternalServerError {
+		if stale := t.staleIfError(orig, cached); stale != nil {
+			discardResponse(resp)
+			return stale, nil
+		}
+	}
+
+	// The ETags the returned response is known by, for evaluating the caller's validators
+	var etags []string
 
 	if resp.StatusCode == http.StatusNotModified {
-		// If the upstream response is 304 Not Modified, we can use the cached response
+		// If the upstream response is 304 Not Modified, we can use the matching candidate response
 
 		// Consume the rest of the response body to ensure the connection can be re-used
 		if _, err := io.Copy(io.Discard, resp.Body); err != nil {
@@ -109,19 +350,49 @@ func (t *transport) RoundTrip(req *http.Request) (resp *http.Response, _ error)
 			return nil, fmt.Errorf("(*http.Response).Body.Close failed: %w", err)
 		}
 
+		// Use the ETag of the response to determine which candidate matched
+		chosen, ok := matchCandidate(candidates, resp.Header.Get("Etag"))
+		if !ok && callerValidators.empty() && len(candidates) > 0 {
+			// No (or an unknown) ETag, but only our own were sent, so assume the most likely candidate
+			chosen, ok = candidates[0], true
+		}
+		if !ok {
+			// None of ours matched, so it must have been one of the caller's own ETags
+			resp.Body = io.NopCloser(strings.NewReader(""))
+			resp.ContentLength = 0
+			reason := "uri-miss"
+			if cached != nil {
+				reason = "stale"
+			}
+			setCacheStatus(resp, cacheStatusForward(t.opts.CacheName, reason, resp.StatusCode, false), "MISS")
+			return resp, nil
+		}
+		etags = append(etags, chosen.etag)
+
 		// Indicate the response was served from cache
-		if cached != nil {
-			setCacheStatus(resp, cacheStatusHit(), "HIT")
+		if chosen.cached == nil {
+			// A speculative ETag guess matched; nothing was ever actually stored for this request (or the Storage
+			// failed), reporting which guess matched takes precedence over reporting the Storage failure
+			setCacheStatus(resp, cacheStatusHitSpeculative(t.opts.CacheName, chosen.speculation.Name), "HIT")
+		} else if storageFailed {
+			setCacheStatus(resp, cacheStatusDetail(cacheStatusHit(t.opts.CacheName), "storage-error"), "HIT")
 		} else {
-			// Our speculative `[]` ETag guess matched; nothing was ever actually stored for this request
-			setCacheStatus(resp, cacheStatusHitSpeculative(), "HIT")
+			setCacheStatus(resp, cacheStatusHit(t.opts.CacheName), "HIT")
 		}
 
 		// Copy in any cached headers that are not already set
-		if cached != nil {
-			for key, vals := range cached.Header {
-				if strings.HasPrefix(key, VaryPrefix) {
-					continue // Skip the X-Varied-* headers, they are "internal" to the cache
+		if chosen.cached != nil {
+			// The stored response was just validated, so it is fresh again
+			if !storageFailed {
+				if err := t.refresh(req, chosen.cached, cached); err != nil {
+					return nil, err
+				}
+			}
+			t.noteCached(req, chosen.cached)
+			etags = append(etags, chosen.cached.Header.Get("Etag"))
+			for key, vals := range chosen.cached.Header {
+				if !t.replayHeader(key) {
+					continue // Skip the X-Varied-* (and similar) "internal" headers and those not replayed
 				}
 				if key == "X-Github-Request-Id" {
 					// Return the original Request-Id header as well
@@ -133,56 +404,48 @@ func (t *transport) RoundTrip(req *http.Request) (resp *http.Response, _ error)
 			}
 
 			// Copy the body and status from the cache
-			resp.StatusCode = cached.StatusCode
-			resp.Status = cached.Status
+			resp.StatusCode = chosen.cached.StatusCode
+			resp.Status = chosen.cached.Status
 		} else {
-			// Our speculative `[]` ETag guess matched the body
+			// A speculative ETag guess matched the body
 			resp.StatusCode = http.StatusOK
 			resp.Status = http.StatusText(http.StatusOK)
 		}
 
+		// The response was just validated, so its age is per the "Date" of the 304 (the "X-Cache-Stored-At"
+		// header tells how long ago the body was stored), per RFC 9111 4.3.4 and 5.1
+		if _, ok := resp.Header["Age"]; !ok {
+			if age, ok := t.age(resp); ok {
+				resp.Header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+			}
+		}
+
 		// As a special case, if the request is a HEAD, we return an empty body
 		if req.Method == http.MethodHead {
 			resp.Body = io.NopCloser(strings.NewReader(""))
 			resp.ContentLength = 0
-		} else if cached != nil {
-			resp.Body = cached.Body
-			resp.ContentLength = cached.ContentLength
+		} else if chosen.cached != nil {
+			resp.Body = chosen.cached.Body
+			resp.ContentLength = chosen.cached.ContentLength
 		} else {
-			// We had no cached response, but our speculative `[]` ETag guess matched the body
-			resp.Body = io.NopCloser(strings.NewReader("[]"))
-			resp.ContentLength = 2
+			// We had no matching cached response, but a speculative ETag guess matched the body
+			resp.Body = io.NopCloser(strings.NewReader(chosen.speculation.Body))
+			resp.ContentLength = int64(len(chosen.speculation.Body))
 		}
 
 	} else {
-		stored := false
-
-		if t.storage != nil && resp.StatusCode == http.StatusOK && req.Method == http.MethodGet && resp.Header.Get("Etag") != "" {
-			// Make a shallow copy of the *http.Response as we're going to modify the headers for storage
-			cacheResp := *resp
-			cacheResp.Header = maps.Clone(resp.Header)
-
-			// Inject fake X-Varied-<header> "response" headers
-			for header := range parseVary(resp.Header) {
-				header = http.CanonicalHeaderKey(header)
-				if vals := req.Header.Values(header); len(vals) > 0 {
-					if header == "Authorization" {
-						vals = []string{HashToken(vals[0])} // Don't leak/cache the raw authentication token
-					}
-					cacheResp.Header[VaryPrefix+header] = vals
-				}
-			}
+		stored, detail := false, ""
 
-			// Store the cached response body as bytes
-			// Per the storage contract, they will restore the Body/ContentLength after consumption
-			if err := t.storage.Put(req.Context(), &cacheResp); err != nil {
-				return resp, fmt.Errorf("(Storage).Put failed: %w", err)
+		if t.opts.Storage != nil && resp.StatusCode == http.StatusOK && req.Method == http.MethodGet && resp.Header.Get("Etag") != "" && !directivesFrom(req.Context()).NoStore {
+			var ok bool
+			if ok, detail = t.storable(resp); ok {
+				var failed bool
+				stored, failed, err = t.store(req, resp, cached)
+				if err != nil {
+					return nil, err
+				}
+				storageFailed = storageFailed || failed
 			}
-			stored = true
-
-			// Restore the copied response body with the cached body
-			resp.Body = cacheResp.Body
-			resp.ContentLength = cacheResp.ContentLength
 		}
 
 		// The response was not served from the cache: if a cached response existed, it turned out to be
@@ -191,21 +454,43 @@ func (t *transport) RoundTrip(req *http.Request) (resp *http.Response, _ error)
 		if cached != nil {
 			reason = "stale"
 		}
-		setCacheStatus(resp, cacheStatusForward(reason, resp.StatusCode, stored), "MISS")
+		cacheStatus := cacheStatusForward(t.opts.CacheName, reason, resp.StatusCode, stored)
+		if storageFailed {
+			detail = "storage-error"
+		}
+		if detail != "" {
+			cacheStatus = cacheStatusDetail(cacheStatus, detail)
+		}
+		setCacheStatus(resp, cacheStatus, "MISS")
+	}
+
+	// If the caller's own validators match the response, they get a real 304 Not Modified
+	if resp.StatusCode == http.StatusOK && callerValidators.match(resp, etags...) {
+		notModified(resp)
 	}
 
 	return resp, nil
 }
 
-// NewTransport creates a new http.RoundTripper that reads/writes responses from the Storage.
-// A nil storage is safe to pass: nothing is ever read from or written to it, but the speculative
-// empty-array ETag guess (see addConditionalHeaders) still applies to every cacheable request.
-func NewTransport(storage Storage, parent http.RoundTripper) http.RoundTripper {
-	if parent == nil {
-		parent = http.DefaultTransport
+// New creates a new Transport configured by the given Options.
+func New(opts Options) *Transport {
+	opts = opts.withDefaults()
+	if opts.PrivateStorage != nil {
+		opts.Storage = &tieredStorage{private: opts.PrivateStorage, shared: opts.Storage}
 	}
-	return &transport{
-		storage: storage,
-		parent:  parent,
+	return &Transport{
+		opts:  opts,
+		now:   time.Now,
+		sleep: sleep,
 	}
 }
+
+// NewTransport creates a new Transport that reads/writes responses from the Storage.
+// A nil storage is safe to pass: nothing is ever read from or written to it, but the speculative
+// ETag guesses (see Speculations) still apply to every cacheable request.
+func NewTransport(storage Storage, parent http.RoundTripper) *Transport {
+	return New(Options{
+		Storage: storage,
+		Parent:  parent,
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 	"testing/iotest"
+	"time"
 )
 
 // errCloseBody wraps an io.Reader with a Close method that returns a fixed error.
@@ -52,6 +53,15 @@ func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	}, nil
 }
 
+// withOperation appends the "operation" parameter the Transport adds for the "GET /repos/foo/bar" requests
+// used throughout the tests.
+func withOperation(cacheStatus string) string {
+	return cacheStatusOperation(cacheStatus, "repos/get")
+}
+
+// speculativeETag is the speculative `[]` ETag guess for a request without any VaryHeaders.
+const speculativeETag = `"4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"`
+
 func TestTransport_RoundTrip(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -91,7 +101,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 			wantStatusCode:  http.StatusCreated,
 			wantBody:        "created",
 			wantXCache:      "MISS",
-			wantCacheStatus: cacheStatusForward("method", http.StatusCreated, false),
+			wantCacheStatus: cacheStatusForward(CacheName, "method", http.StatusCreated, false),
 		},
 		{
 			name:      "uncacheable request (POST), upstream error",
@@ -158,7 +168,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 			wantStatusCode:  http.StatusOK,
 			wantBody:        "content",
 			wantXCache:      "MISS",
-			wantCacheStatus: cacheStatusForward("uri-miss", http.StatusOK, true),
+			wantCacheStatus: withOperation(cacheStatusForward(CacheName, "uri-miss", http.StatusOK, true)),
 		},
 		{
 			name:      "cache miss, upstream OK with Vary, stores X-Varied-* headers",
@@ -218,7 +228,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 			wantStatusCode:  http.StatusOK,
 			wantBody:        "content",
 			wantXCache:      "MISS",
-			wantCacheStatus: cacheStatusForward("uri-miss", http.StatusOK, true),
+			wantCacheStatus: withOperation(cacheStatusForward(CacheName, "uri-miss", http.StatusOK, true)),
 		},
 		{
 			name:      "cache miss, speculative empty array 304",
@@ -248,7 +258,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 			wantStatusCode:  http.StatusOK,
 			wantBody:        "[]",
 			wantXCache:      "HIT",
-			wantCacheStatus: cacheStatusHitSpeculative(),
+			wantCacheStatus: withOperation(cacheStatusHitSpeculative(CacheName, "empty-array")),
 		},
 		{
 			name:      "storage error on Get",
@@ -382,7 +392,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 			wantStatusCode:  http.StatusOK,
 			wantBody:        "",
 			wantXCache:      "HIT",
-			wantCacheStatus: cacheStatusHit(),
+			wantCacheStatus: withOperation(cacheStatusHit(CacheName)),
 		},
 		{
 			name:      "upstream 304 Not Modified, cache hit",
@@ -408,8 +418,8 @@ func TestTransport_RoundTrip(t *testing.T) {
 					roundTripFunc: func(req *http.Request) (*http.Response, error) {
 						// transport logic will call addConditionalHeaders, which reads cached body if vary differs
 						// Here vary is default (empty), so it should use cached Etag
-						if req.Header.Get("If-None-Match") != `"tag1"` {
-							t.Errorf("expected If-None-Match \"tag1\", got %q", req.Header.Get("If-None-Match"))
+						if got, want := req.Header.Get("If-None-Match"), `"tag1", `+speculativeETag; got != want {
+							t.Errorf("expected If-None-Match %q, got %q", want, got)
 						}
 						return &http.Response{
 							StatusCode: http.StatusNotModified,
@@ -422,7 +432,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 			wantStatusCode:  http.StatusOK,
 			wantBody:        "cached content",
 			wantXCache:      "HIT",
-			wantCacheStatus: cacheStatusHit(),
+			wantCacheStatus: withOperation(cacheStatusHit(CacheName)),
 		},
 		{
 			name:      "upstream 200 OK (modified), cache miss, stores response",
@@ -466,7 +476,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 			wantStatusCode:  http.StatusOK,
 			wantBody:        "new content",
 			wantXCache:      "MISS",
-			wantCacheStatus: cacheStatusForward("stale", http.StatusOK, true),
+			wantCacheStatus: withOperation(cacheStatusForward(CacheName, "stale", http.StatusOK, true)),
 		},
 		{
 			name:      "upstream error",
@@ -535,7 +545,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 			wantStatusCode:  http.StatusOK,
 			wantBody:        "[]",
 			wantXCache:      "HIT",
-			wantCacheStatus: cacheStatusHitSpeculative(),
+			wantCacheStatus: withOperation(cacheStatusHitSpeculative(CacheName, "empty-array")),
 		},
 		{
 			name:      "nil storage, upstream 200 OK is not stored",
@@ -561,7 +571,150 @@ func TestTransport_RoundTrip(t *testing.T) {
 			wantStatusCode:  http.StatusOK,
 			wantBody:        "content",
 			wantXCache:      "MISS",
-			wantCacheStatus: cacheStatusForward("uri-miss", http.StatusOK, false),
+			wantCacheStatus: withOperation(cacheStatusForward(CacheName, "uri-miss", http.StatusOK, false)),
+		},
+		{
+			name:      "caller If-None-Match matches cached, returns 304",
+			reqMethod: http.MethodGet,
+			reqURL:    "https://api.github.com/repos/foo/bar",
+			reqHeader: http.Header{
+				"If-None-Match": []string{`"tag1"`},
+			},
+			setupStorage: func(t *testing.T) Storage {
+				return &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						return &http.Response{
+							StatusCode:    http.StatusOK,
+							Status:        "200 OK",
+							Header:        http.Header{"Etag": []string{`"tag1"`}},
+							Body:          io.NopCloser(strings.NewReader("cached content")),
+							ContentLength: 14,
+						}, nil
+					},
+				}
+			},
+			setupParent: func(t *testing.T) http.RoundTripper {
+				return &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						if got, want := req.Header.Get("If-None-Match"), `"tag1", `+speculativeETag; got != want {
+							t.Errorf("expected If-None-Match %q, got %q", want, got)
+						}
+						return &http.Response{
+							StatusCode: http.StatusNotModified,
+							Header:     http.Header{"Etag": []string{`"tag1"`}},
+							Body:       io.NopCloser(strings.NewReader("")),
+						}, nil
+					},
+				}
+			},
+			wantStatusCode:  http.StatusNotModified,
+			wantBody:        "",
+			wantXCache:      "HIT",
+			wantCacheStatus: withOperation(cacheStatusHit(CacheName)),
+		},
+		{
+			name:      "caller If-None-Match is outdated, returns cached body",
+			reqMethod: http.MethodGet,
+			reqURL:    "https://api.github.com/repos/foo/bar",
+			reqHeader: http.Header{
+				"If-None-Match": []string{`"tag0"`},
+			},
+			setupStorage: func(t *testing.T) Storage {
+				return &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						return &http.Response{
+							StatusCode:    http.StatusOK,
+							Status:        "200 OK",
+							Header:        http.Header{"Etag": []string{`"tag1"`}},
+							Body:          io.NopCloser(strings.NewReader("cached content")),
+							ContentLength: 14,
+						}, nil
+					},
+				}
+			},
+			setupParent: func(t *testing.T) http.RoundTripper {
+				return &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						if got, want := req.Header.Get("If-None-Match"), `"tag1", `+speculativeETag+`, "tag0"`; got != want {
+							t.Errorf("expected If-None-Match %q, got %q", want, got)
+						}
+						return &http.Response{
+							StatusCode: http.StatusNotModified,
+							Header:     http.Header{"Etag": []string{`"tag1"`}},
+							Body:       io.NopCloser(strings.NewReader("")),
+						}, nil
+					},
+				}
+			},
+			wantStatusCode:  http.StatusOK,
+			wantBody:        "cached content",
+			wantXCache:      "HIT",
+			wantCacheStatus: withOperation(cacheStatusHit(CacheName)),
+		},
+		{
+			name:      "caller If-None-Match, upstream 304 without an ETag, returns 304",
+			reqMethod: http.MethodGet,
+			reqURL:    "https://api.github.com/repos/foo/bar",
+			reqHeader: http.Header{
+				"If-None-Match": []string{`"tag0"`},
+			},
+			setupStorage: func(t *testing.T) Storage {
+				return &mockStorage{
+					getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+						return &http.Response{
+							StatusCode:    http.StatusOK,
+							Status:        "200 OK",
+							Header:        http.Header{"Etag": []string{`"tag1"`}},
+							Body:          io.NopCloser(strings.NewReader("cached content")),
+							ContentLength: 14,
+						}, nil
+					},
+				}
+			},
+			setupParent: func(t *testing.T) http.RoundTripper {
+				return &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						return &http.Response{
+							StatusCode: http.StatusNotModified,
+							Header:     make(http.Header),
+							Body:       io.NopCloser(strings.NewReader("")),
+						}, nil
+					},
+				}
+			},
+			wantStatusCode:  http.StatusNotModified,
+			wantBody:        "",
+			wantXCache:      "MISS",
+			wantCacheStatus: withOperation(cacheStatusForward(CacheName, "stale", http.StatusNotModified, false)),
+		},
+		{
+			name:      "cache miss, caller If-None-Match matches upstream, returns 304",
+			reqMethod: http.MethodGet,
+			reqURL:    "https://api.github.com/repos/foo/bar",
+			reqHeader: http.Header{
+				"If-None-Match": []string{`"tag1"`},
+			},
+			setupStorage: func(t *testing.T) Storage {
+				return &mockStorage{}
+			},
+			setupParent: func(t *testing.T) http.RoundTripper {
+				return &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						if got := req.Header.Get("If-None-Match"); !strings.HasSuffix(got, `, "tag1"`) {
+							t.Errorf("expected If-None-Match to include %q, got %q", `"tag1"`, got)
+						}
+						return &http.Response{
+							StatusCode: http.StatusNotModified,
+							Header:     http.Header{"Etag": []string{`"tag1"`}},
+							Body:       io.NopCloser(strings.NewReader("")),
+						}, nil
+					},
+				}
+			},
+			wantStatusCode:  http.StatusNotModified,
+			wantBody:        "",
+			wantXCache:      "MISS",
+			wantCacheStatus: withOperation(cacheStatusForward(CacheName, "uri-miss", http.StatusNotModified, false)),
 		},
 	}
 
@@ -648,27 +801,75 @@ func TestCacheName_override(t *testing.T) {
 	}
 	defer resp.Body.Close()
 
-	want := `my-custom-cache; fwd=uri-miss; fwd-status=200; stored`
+	want := withOperation(`my-custom-cache; fwd=uri-miss; fwd-status=200; stored`)
 	if got := resp.Header.Get("Cache-Status"); got != want {
 		t.Errorf("RoundTrip() %s = %q, want %q", "Cache-Status", got, want)
 	}
 }
 
+func TestNew_perInstanceOptions(t *testing.T) {
+	parent := &mockRoundTripper{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Vary": []string{"Accept"},
+				},
+				Body: io.NopCloser(strings.NewReader("content")),
+			}
+			resp.Header.Set("Etag", "tag1")
+			return resp, nil
+		},
+	}
+	for _, tt := range []struct {
+		cacheName  string
+		varyPrefix string
+	}{
+		{cacheName: "github.com", varyPrefix: "X-Alpha-"},
+		{cacheName: "ghes.example.com", varyPrefix: "X-Beta-"},
+	} {
+		tr := New(Options{
+			Storage: &mockStorage{
+				putFunc: func(ctx context.Context, resp *http.Response) error {
+					if got := resp.Header.Get(tt.varyPrefix + "Accept"); got != "application/json" {
+						t.Errorf("%sAccept = %q, want %q", tt.varyPrefix, got, "application/json")
+					}
+					return nil
+				},
+			},
+			Parent:     parent,
+			CacheName:  tt.cacheName,
+			VaryPrefix: tt.varyPrefix,
+		})
+
+		req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		resp.Body.Close()
+
+		if got, want := resp.Header.Get("Cache-Status"), withOperation(cacheStatusForward(tt.cacheName, "uri-miss", http.StatusOK, true)); got != want {
+			t.Errorf("RoundTrip() %s = %q, want %q", "Cache-Status", got, want)
+		}
+	}
+}
+
 func TestNewTransport_defaultParent(t *testing.T) {
 	tr := NewTransport(&mockStorage{}, nil)
-	impl, ok := tr.(*transport)
-	if !ok {
-		t.Fatalf("NewTransport() returned %T, want *transport", tr)
-	}
-	if impl.parent != http.DefaultTransport {
-		t.Errorf("NewTransport() parent = %v, want http.DefaultTransport", impl.parent)
+	if tr.opts.Parent != http.DefaultTransport {
+		t.Errorf("NewTransport() parent = %v, want http.DefaultTransport", tr.opts.Parent)
 	}
 }
 
-// TestTransport_RoundTrip_PutError documents current behavior when Storage.Put fails:
-// RoundTrip returns a non-nil *http.Response alongside the error. Most http.RoundTripper
-// callers (including net/http.Client) discard/never close the response when err != nil,
-// which can leak the underlying body/connection.
+// TestTransport_RoundTrip_PutError ensures that when Storage.Put fails (and the StorageErrorPolicy is
+// FailClosed) RoundTrip honors the http.RoundTripper contract by returning only the error, closing the
+// upstream response body rather than leaking it to callers that never close it when err != nil.
 func TestTransport_RoundTrip_PutError(t *testing.T) {
 	storage := &mockStorage{
 		getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
@@ -678,12 +879,13 @@ func TestTransport_RoundTrip_PutError(t *testing.T) {
 			return errors.New("put failed")
 		},
 	}
+	closed := false
 	parent := &mockRoundTripper{
 		roundTripFunc: func(req *http.Request) (*http.Response, error) {
 			resp := &http.Response{
 				StatusCode: http.StatusOK,
 				Header:     http.Header{},
-				Body:       io.NopCloser(strings.NewReader("content")),
+				Body:       &closeTrackingBody{Reader: strings.NewReader("content"), closed: &closed},
 			}
 			resp.Header.Set("Etag", "tag1")
 			return resp, nil
@@ -700,16 +902,241 @@ func TestTransport_RoundTrip_PutError(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected RoundTrip to return an error when Storage.Put fails")
 	}
-	if resp == nil {
-		t.Fatal("expected RoundTrip to return a non-nil response alongside the Put error (current behavior)")
+	var serr *StorageError
+	if !errors.As(err, &serr) || serr.Op != "Put" {
+		t.Errorf("RoundTrip() error = %v, want *StorageError for Put", err)
+	}
+	if resp != nil {
+		t.Errorf("RoundTrip() returned non-nil response %v alongside the error", resp)
+	}
+	if !closed {
+		t.Error("RoundTrip() did not close the upstream response body")
+	}
+}
+
+// closeTrackingBody wraps an io.Reader, recording when it is closed.
+type closeTrackingBody struct {
+	io.Reader
+	closed *bool
+}
+
+func (c *closeTrackingBody) Close() error {
+	*c.closed = true
+	return nil
+}
+
+func TestTransport_RoundTrip_FailOpen(t *testing.T) {
+	tests := map[string]struct {
+		Storage         Storage
+		WantOp          string
+		WantCacheStatus string
+	}{
+		"get": {
+			Storage: &mockStorage{
+				getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+					return nil, errors.New("get failed")
+				},
+			},
+			WantOp:          "Get",
+			WantCacheStatus: cacheStatusDetail(cacheStatusForward(CacheName, "uri-miss", http.StatusOK, true), "storage-error"),
+		},
+		"put": {
+			Storage: &mockStorage{
+				putFunc: func(ctx context.Context, resp *http.Response) error {
+					// Consume the body without restoring it, the transport must still return it intact
+					_, _ = io.ReadAll(resp.Body)
+					return errors.New("put failed")
+				},
+			},
+			WantOp:          "Put",
+			WantCacheStatus: cacheStatusDetail(cacheStatusForward(CacheName, "uri-miss", http.StatusOK, false), "storage-error"),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var reported []error
+			tr := New(Options{
+				Storage: test.Storage,
+				Parent: &mockRoundTripper{
+					roundTripFunc: func(req *http.Request) (*http.Response, error) {
+						resp := &http.Response{
+							StatusCode: http.StatusOK,
+							Header:     http.Header{},
+							Body:       io.NopCloser(strings.NewReader("content")),
+						}
+						resp.Header.Set("Etag", "tag1")
+						return resp, nil
+					},
+				},
+				StorageErrorPolicy: FailOpen,
+				OnStorageError: func(req *http.Request, err error) {
+					reported = append(reported, err)
+				},
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			resp, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			defer resp.Body.Close()
+
+			if body, _ := io.ReadAll(resp.Body); string(body) != "content" {
+				t.Errorf("RoundTrip() body = %q, want %q", body, "content")
+			}
+			if got, want := resp.Header.Get("Cache-Status"), withOperation(test.WantCacheStatus); got != want {
+				t.Errorf("RoundTrip() %s = %q, want %q", "Cache-Status", got, want)
+			}
+			if len(reported) != 1 {
+				t.Fatalf("OnStorageError called %d times, want 1", len(reported))
+			}
+			var serr *StorageError
+			if !errors.As(reported[0], &serr) || serr.Op != test.WantOp {
+				t.Errorf("OnStorageError(%v), want *StorageError for %s", reported[0], test.WantOp)
+			}
+		})
+	}
+}
+
+func TestTransport_RoundTrip_FailOpenSpeculative(t *testing.T) {
+	var reported []error
+	tr := New(Options{
+		Storage: &mockStorage{
+			getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				return nil, errors.New("get failed")
+			},
+		},
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				// Nothing could be read from the Storage, so the only candidate is the speculative guess
+				return &http.Response{
+					StatusCode: http.StatusNotModified,
+					Header:     http.Header{"Etag": []string{req.Header.Get("If-None-Match")}},
+					Body:       http.NoBody,
+				}, nil
+			},
+		},
+		StorageErrorPolicy: FailOpen,
+		OnStorageError: func(req *http.Request, err error) {
+			reported = append(reported, err)
+		},
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar/issues", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
 	}
 	defer resp.Body.Close()
 
-	body, readErr := io.ReadAll(resp.Body)
-	if readErr != nil {
-		t.Fatalf("failed to read response body: %v", readErr)
+	if body, _ := io.ReadAll(resp.Body); string(body) != "[]" {
+		t.Errorf("RoundTrip() body = %q, want %q", body, "[]")
+	}
+	if got, want := resp.Header.Get("Cache-Status"), cacheStatusOperation(cacheStatusHitSpeculative(CacheName, "empty-array"), "issues/list-for-repo"); got != want {
+		t.Errorf("RoundTrip() Cache-Status = %q, want %q", got, want)
+	}
+	if info, _ := Info(resp); info.Speculation != "empty-array" {
+		t.Errorf("Info().Speculation = %q, want %q", info.Speculation, "empty-array")
+	}
+	if len(reported) != 1 {
+		t.Errorf("OnStorageError called %d times, want 1", len(reported))
+	}
+}
+func TestTransport_RoundTrip_Provenance(t *testing.T) {
+	now := time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)
+	storedAt := now
+	var stored *http.Response
+	tr := New(Options{
+		Storage: &mockStorage{
+			getFunc: func(ctx context.Context, req *http.Request) (*http.Response, error) {
+				if stored == nil {
+					return nil, nil
+				}
+				resp := *stored
+				resp.Body = io.NopCloser(strings.NewReader("content"))
+				return &resp, nil
+			},
+			putFunc: func(ctx context.Context, resp *http.Response) error {
+				stored = resp
+				return nil
+			},
+		},
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				header := http.Header{
+					"Etag":                          []string{`"tag1"`},
+					"Date":                          []string{now.Format(http.TimeFormat)},
+					"X-Github-Request-Id":           []string{"upstream-id"},
+					"X-Github-Api-Version-Selected": []string{"2022-11-28"},
+				}
+				if strings.Contains(req.Header.Get("If-None-Match"), `"tag1"`) {
+					header.Set("X-Github-Request-Id", "revalidate-id")
+					return &http.Response{StatusCode: http.StatusNotModified, Header: header, Body: http.NoBody}, nil
+				}
+				return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader("content"))}, nil
+			},
+		},
+	})
+	tr.now = func() time.Time { return now }
+
+	roundTrip := func() *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := roundTrip()
+	if _, ok := resp.Header[StoredAtHeader]; ok {
+		t.Errorf("RoundTrip() miss returned the %s header", StoredAtHeader)
+	}
+	for key, want := range map[string]string{
+		StoredAtHeader:        storedAt.Format(http.TimeFormat),
+		StoredByHeader:        HashToken("Bearer token"),
+		APIVersionHeader:      "2022-11-28",
+		"X-Github-Request-Id": "upstream-id",
+	} {
+		if got := stored.Header.Get(key); got != want {
+			t.Errorf("stored %s = %q, want %q", key, got, want)
+		}
+	}
+
+	now = now.Add(90 * time.Second)
+	resp = roundTrip()
+	for key, want := range map[string]string{
+		"Age":                 "0",
+		StoredAtHeader:        storedAt.Format(http.TimeFormat),
+		CachedRequestIDHeader: "upstream-id",
+	} {
+		if got := resp.Header.Get(key); got != want {
+			t.Errorf("RoundTrip() %s = %q, want %q", key, got, want)
+		}
+	}
+	for _, key := range []string{StoredByHeader, APIVersionHeader} {
+		if _, ok := resp.Header[key]; ok {
+			t.Errorf("RoundTrip() leaked the internal %s header", key)
+		}
+	}
+	info, ok := Info(resp)
+	if !ok {
+		t.Fatal("Info() ok = false, want true")
 	}
-	if string(body) != "content" {
-		t.Errorf("response body = %q, want %q", body, "content")
+	if !info.StoredAt.Equal(storedAt) || info.StoredBy != HashToken("Bearer token") || info.APIVersion != "2022-11-28" {
+		t.Errorf("Info() = %+v, want the provenance of the stored response", info)
 	}
 }
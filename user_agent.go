@@ -7,6 +7,7 @@ import (
 
 // Responses from the GitHub REST API are pretty-printed if the User-Agent contains "curl", "Wget", "Safari" or "Firefox".
 // This breaks the ETag calculation, so we need to substitute these strings if present in the User-Agent.
+// It may be overridden per Transport via Options.UserAgentReplacer.
 var UserAgentReplacer = strings.NewReplacer(
 	"curl", "cUrL",
 	"Wget", "wGeT",
@@ -14,8 +15,9 @@ var UserAgentReplacer = strings.NewReplacer(
 	"Firefox", "fIrEfOx",
 )
 
-func replaceUserAgent(headers http.Header) {
+// replaceUserAgent rewrites the "User-Agent" header (if present) using the configured UserAgentReplacer.
+func (t *Transport) replaceUserAgent(headers http.Header) {
 	if ua := headers.Get("User-Agent"); ua != "" {
-		headers.Set("User-Agent", UserAgentReplacer.Replace(ua))
+		headers.Set("User-Agent", t.opts.UserAgentReplacer.Replace(ua))
 	}
 }
@@ -34,7 +34,7 @@ func TestReplaceUserAgent(t *testing.T) {
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			replaceUserAgent(test.Headers)
+			NewTransport(nil, nil).replaceUserAgent(test.Headers)
 			if !reflect.DeepEqual(test.Headers, test.Expected) {
 				t.Errorf("replaceUserAgent(%v) = %v, want %v", test.Headers, test.Headers, test.Expected)
 			}
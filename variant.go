@@ -0,0 +1,154 @@
+package ghtransport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// VariantsHeader is the internal header listing the IDs of the variants stored for a URL, most recent first.
+// It is only maintained on the primary response stored for the URL, see Options.MaxVariants.
+const VariantsHeader = "X-Cache-Variants"
+
+// variantID identifies a variant of a URL by the X-Varied-* headers it was (or would be) stored with.
+func variantID(varied http.Header) string {
+	h := sha256.New()
+	for _, key := range slices.Sorted(maps.Keys(varied)) {
+		for _, val := range varied[key] {
+			h.Write([]byte(key + ": " + val + "\n"))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// storedVaried extracts the X-Varied-* headers of a stored response.
+func (t *Transport) storedVaried(headers http.Header) http.Header {
+	varied := make(http.Header)
+	for key, vals := range headers {
+		if strings.HasPrefix(key, t.opts.VaryPrefix) {
+			varied[key] = vals
+		}
+	}
+	return varied
+}
+
+// variants parses the VariantsHeader of a (primary) stored response.
+func variants(headers http.Header) (ids []string) {
+	for _, val := range headers.Values(VariantsHeader) {
+		for id := range strings.SplitSeq(val, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// variantRequest returns a shallow clone of req whose URL identifies the given variant in the Storage.
+// The fragment is never sent upstream, so it cannot collide with the URL of a real request.
+func variantRequest(req *http.Request, id string) *http.Request {
+	variant := req.WithContext(req.Context())
+	u := *req.URL
+	u.Fragment = "variant=" + id
+	variant.URL = &u
+	return variant
+}
+
+// selectVariant replaces the primary cached response with the stored variant matching the request's
+// X-Varied-* headers exactly (if any), so its ETag can be reused as-is. The returned response always
+// carries the VariantsHeader of the primary response, as that is where it is maintained.
+func (t *Transport) selectVariant(req *http.Request, cached *http.Response) (*http.Response, error) {
+	if t.opts.MaxVariants <= 1 || cached == nil || t.identicalVary(req, cached) {
+		return cached, nil
+	}
+	id := variantID(t.varied(req, cached.Header))
+	if !slices.Contains(variants(cached.Header), id) {
+		return cached, nil
+	}
+	variant, err := t.opts.Storage.Get(req.Context(), variantRequest(req, id))
+	if err != nil {
+		return cached, t.storageError(req, "Get", err)
+	}
+	if variant == nil || !t.identicalVary(req, variant) {
+		discardResponse(variant)
+		return cached, nil
+	}
+	if variant.Header == nil {
+		variant.Header = make(http.Header)
+	}
+	variant.Header[VariantsHeader] = cached.Header[VariantsHeader]
+	discardResponse(cached)
+	return variant, nil
+}
+
+// otherVariants retrieves every stored variant of the URL other than the cached response itself, such that
+// they can be candidates for a 304 Not Modified as well.
+func (t *Transport) otherVariants(req *http.Request, cached *http.Response) (others []*http.Response, _ error) {
+	if t.opts.MaxVariants <= 1 || cached == nil {
+		return nil, nil
+	}
+	self := variantID(t.storedVaried(cached.Header))
+	for _, id := range variants(cached.Header) {
+		if id == self {
+			continue
+		}
+		variant, err := t.opts.Storage.Get(req.Context(), variantRequest(req, id))
+		if err != nil {
+			if err := t.storageError(req, "Get", err); err != nil {
+				for _, other := range others {
+					discardResponse(other)
+				}
+				return nil, err
+			}
+			continue
+		}
+		if variant != nil {
+			others = append(others, variant)
+		}
+	}
+	return others, nil
+}
+
+// storeVariant stores the response (whose body has already been read into memory) as a variant of the URL,
+// recording it as the most recent variant in the primary response's VariantsHeader. Evicted variants beyond
+// MaxVariants are deleted if the Storage implements Deleter.
+func (t *Transport) storeVariant(req *http.Request, cacheResp *http.Response, body []byte, cached *http.Response) error {
+	id := variantID(t.storedVaried(cacheResp.Header))
+
+	// Maintain the list of variants, most recent first
+	ids := []string{id}
+	if cached != nil {
+		for _, other := range variants(cached.Header) {
+			if other != id {
+				ids = append(ids, other)
+			}
+		}
+	}
+	var evicted []string
+	if len(ids) > t.opts.MaxVariants {
+		ids, evicted = ids[:t.opts.MaxVariants], ids[t.opts.MaxVariants:]
+	}
+	cacheResp.Header.Set(VariantsHeader, strings.Join(ids, ", "))
+
+	variant := *cacheResp
+	variant.Request = variantRequest(req, id)
+	variant.Body = io.NopCloser(bytes.NewReader(body))
+	variant.ContentLength = int64(len(body))
+	if err := t.opts.Storage.Put(req.Context(), &variant); err != nil {
+		return err
+	}
+
+	if deleter, ok := t.opts.Storage.(Deleter); ok {
+		for _, id := range evicted {
+			if err := deleter.Delete(req.Context(), variantRequest(req, id)); err != nil {
+				_ = t.storageError(req, "Delete", err) // It is simply no longer reachable
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package ghtransport
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bored-engineer/github-conditional-http-transport/memory"
+)
+
+func TestTransport_RoundTrip_MaxVariants(t *testing.T) {
+	storage := memory.NewStorage()
+
+	// The upstream returns a different body per token, revalidating with the token's "real" ETag
+	bodies := map[string]string{
+		"Bearer alpha": "alpha body",
+		"Bearer beta":  "beta body",
+		"Bearer gamma": "gamma body",
+	}
+	var gotIfNoneMatch string
+	tr := New(Options{
+		Storage: storage,
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				gotIfNoneMatch = req.Header.Get("If-None-Match")
+				etag := `"` + req.Header.Get("Authorization") + `"`
+				if slices.Contains(strings.Split(gotIfNoneMatch, ", "), etag) {
+					return &http.Response{
+						StatusCode: http.StatusNotModified,
+						Header:     http.Header{"Etag": []string{etag}},
+						Body:       io.NopCloser(strings.NewReader("")),
+					}, nil
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header: http.Header{
+						"Etag": []string{etag},
+						"Vary": []string{"Accept, Authorization"},
+					},
+					Body: io.NopCloser(strings.NewReader(bodies[req.Header.Get("Authorization")])),
+				}, nil
+			},
+		},
+		MaxVariants: 2,
+	})
+
+	roundTrip := func(authorization string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", authorization)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		defer resp.Body.Close()
+		if body, _ := io.ReadAll(resp.Body); string(body) != bodies[authorization] {
+			t.Errorf("RoundTrip(%s) body = %q, want %q", authorization, body, bodies[authorization])
+		}
+		if resp.Header.Get(VariantsHeader) != "" {
+			t.Errorf("RoundTrip(%s) leaked the internal %s header", authorization, VariantsHeader)
+		}
+		return resp
+	}
+	variantStored := func(authorization string) bool {
+		req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+		req.Header.Set("Authorization", authorization)
+		id := variantID(tr.varied(req, http.Header{"Vary": []string{"Accept, Authorization"}}))
+		_, ok := storage.Map.Load(variantRequest(req, id).URL.String())
+		return ok
+	}
+
+	roundTrip("Bearer alpha")
+	roundTrip("Bearer beta")
+
+	// The alpha variant must be selected (and its ETag reused as-is) even though beta was stored last
+	if resp := roundTrip("Bearer alpha"); resp.Header.Get("X-Cache") != "HIT" {
+		t.Errorf("RoundTrip(alpha) X-Cache = %q, want %q", resp.Header.Get("X-Cache"), "HIT")
+	}
+	if !strings.HasPrefix(gotIfNoneMatch, `"Bearer alpha", `) {
+		t.Errorf("RoundTrip(alpha) If-None-Match = %q, want prefix %q", gotIfNoneMatch, `"Bearer alpha", `)
+	}
+
+	// Only the revalidated alpha variant is refreshed, the primary response remains the beta variant
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+	req.Header.Set("Authorization", "Bearer alpha")
+	variant, err := storage.Get(req.Context(), variantRequest(req, variantID(tr.varied(req, http.Header{"Vary": []string{"Accept, Authorization"}}))))
+	if err != nil || variant == nil {
+		t.Fatalf("(Storage).Get of the alpha variant = %v, %v", variant, err)
+	}
+	if variant.Header.Get(ValidatedAtHeader) == "" {
+		t.Errorf("alpha variant %s is not set", ValidatedAtHeader)
+	}
+	primary, err := storage.Get(req.Context(), req)
+	if err != nil || primary == nil {
+		t.Fatalf("(Storage).Get of the primary response = %v, %v", primary, err)
+	}
+	if body, _ := io.ReadAll(primary.Body); string(body) != bodies["Bearer beta"] {
+		t.Errorf("primary response body = %q, want %q", body, bodies["Bearer beta"])
+	}
+
+	// Storing a third variant must evict the least recently stored one
+	roundTrip("Bearer gamma")
+	for authorization, want := range map[string]bool{
+		"Bearer alpha": false,
+		"Bearer beta":  true,
+		"Bearer gamma": true,
+	} {
+		if got := variantStored(authorization); got != want {
+			t.Errorf("variant for %s stored = %v, want %v", authorization, got, want)
+		}
+	}
+}
+
+func TestTransport_RoundTrip_MaxVariantsCandidates(t *testing.T) {
+	storage := memory.NewStorage()
+	bodies := map[string]string{
+		"Bearer alpha": "alpha body",
+		"Bearer beta":  "beta body",
+	}
+	tr := New(Options{
+		Storage: storage,
+		Parent: &mockRoundTripper{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				etags := strings.Split(req.Header.Get("If-None-Match"), ", ")
+				if body, ok := bodies[req.Header.Get("Authorization")]; ok {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header: http.Header{
+							"Etag": []string{`"` + req.Header.Get("Authorization") + `"`},
+							"Vary": []string{"Accept, Authorization"},
+						},
+						Body: io.NopCloser(strings.NewReader(body)),
+					}, nil
+				}
+				// Every candidate is listed: beta (most recently stored), alpha, then the speculative guess
+				if len(etags) != 3 {
+					t.Errorf("If-None-Match = %q, want 3 candidates", req.Header.Get("If-None-Match"))
+				}
+				// Pretend the older alpha variant is the one that matches
+				return &http.Response{
+					StatusCode: http.StatusNotModified,
+					Header:     http.Header{"Etag": []string{etags[1]}},
+					Body:       io.NopCloser(strings.NewReader("")),
+				}, nil
+			},
+		},
+		MaxVariants: 2,
+	})
+
+	roundTrip := func(authorization string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "https://api.github.com/repos/foo/bar", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", authorization)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	roundTrip("Bearer alpha")
+	roundTrip("Bearer beta")
+
+	resp, body := roundTrip("Bearer gamma")
+	if body != bodies["Bearer alpha"] {
+		t.Errorf("RoundTrip(gamma) body = %q, want %q", body, bodies["Bearer alpha"])
+	}
+	if resp.Header.Get("X-Cache") != "HIT" {
+		t.Errorf("RoundTrip(gamma) X-Cache = %q, want %q", resp.Header.Get("X-Cache"), "HIT")
+	}
+}
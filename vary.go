@@ -7,11 +7,12 @@ import (
 	"strings"
 )
 
-// VaryPrefix is used to store the "Vary" header values from the _request_ as fake "response" headers.
+// VaryPrefix is the default prefix used to store the "Vary" header values from the _request_ as fake
+// "response" headers. It may be overridden per Transport via Options.VaryPrefix.
 var VaryPrefix = "X-Varied-"
 
 // identicalVary checks if the Vary headers are all identical to the cached values.
-func identicalVary(req *http.Request, cached *http.Response) bool {
+func (t *Transport) identicalVary(req *http.Request, cached *http.Response) bool {
 	for header := range parseVary(cached.Header) {
 		switch header {
 		case "*":
@@ -20,12 +21,12 @@ func identicalVary(req *http.Request, cached *http.Response) bool {
 			return false
 		case "Authorization":
 			// Special case, we need to hash the Authorization header before comparing
-			if HashToken(req.Header.Get("Authorization")) != cached.Header.Get(VaryPrefix+header) {
+			if HashToken(req.Header.Get("Authorization")) != cached.Header.Get(t.opts.VaryPrefix+header) {
 				return false
 			}
 		default:
 			// Compare all values (not just the first), matching how they're stored in transport.go
-			if !slices.Equal(req.Header.Values(header), cached.Header.Values(VaryPrefix+header)) {
+			if !slices.Equal(req.Header.Values(header), cached.Header.Values(t.opts.VaryPrefix+header)) {
 				return false
 			}
 		}
@@ -47,3 +48,9 @@ func parseVary(headers http.Header) iter.Seq[string] {
 		}
 	}
 }
+
+// internalHeader reports if the (stored) response header is "internal" to the cache, such that it should
+// never be returned to the caller.
+func (t *Transport) internalHeader(key string) bool {
+	return strings.HasPrefix(key, t.opts.VaryPrefix) || key == VariantsHeader || key == StoredByHeader || key == APIVersionHeader
+}
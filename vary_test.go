@@ -118,7 +118,7 @@ func Test_identicalVary(t *testing.T) {
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			if got := identicalVary(test.Request, test.Cached); got != test.Expected {
+			if got := NewTransport(nil, nil).identicalVary(test.Request, test.Cached); got != test.Expected {
 				t.Errorf("identicalVary(%v, %v) = %v, want %v", test.Request, test.Cached, got, test.Expected)
 			}
 		})